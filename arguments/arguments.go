@@ -2,6 +2,7 @@ package arguments
 
 import (
 	"text/template"
+	"time"
 
 	"github.com/gobwas/glob"
 )
@@ -14,9 +15,42 @@ const (
 	FetchAll
 )
 
+type FetchTags int
+
+const (
+	// FetchTagsFollow fetches tags pointing into fetched history, as git does by default
+	FetchTagsFollow FetchTags = iota
+	FetchTagsAll
+	FetchTagsNone
+)
+
+type BackendType int
+
+const (
+	BackendGoGit BackendType = iota
+	BackendGit
+)
+
+// SortOrder is the order of reported verdicts
+type SortOrder int
+
+const (
+	// SortPath orders verdicts by repository path and then by kind
+	SortPath SortOrder = iota
+	// SortKind orders verdicts by kind, in the order of checks, and then by repository path
+	SortKind
+	// SortAge orders verdicts from the oldest work to the newest
+	SortAge
+)
+
+// FormatDefault is the format of the plain report, other formats are reporters of the assayer package
+const FormatDefault = "default"
+
 type Arguments struct {
 	Unmodified      bool
-	Modified        bool
+	Staged          bool
+	Unstaged        bool
+	Conflicted      bool
 	Untracked       bool
 	StashedChanges  bool
 	RemoteBehind    bool
@@ -28,26 +62,139 @@ type Arguments struct {
 	Exclude *glob.Glob
 	Deep    bool
 	Verbose bool
+	Rescan  bool
+	NoCache bool
+	// SkipIgnored skips repositories ignored with `git config assayer.ignore true`
+	SkipIgnored bool
+
+	Files      bool
+	FilesLimit int
+
+	FetchType   FetchType
+	FetchGroup  *glob.Glob
+	FetchHost   *glob.Glob
+	FetchPath   *glob.Glob
+	FetchRemote *glob.Glob
+	SSHKeys     []string
+	ProbeRemote bool
+	FetchPrune  bool
+	FetchTags   FetchTags
 
-	FetchType  FetchType
-	FetchGroup *glob.Glob
+	FetchTimeout  time.Duration
+	FetchJobs     int
+	FetchHostJobs int
+	FetchRetries  int
+	FetchMaxAge   time.Duration
+
+	Backend BackendType
 
 	Reporter *template.Template
+	Format   string
+	Sort     SortOrder
+	// Stream reports verdicts as they are found instead of sorting them
+	Stream bool
 }
 
 func DefaultArguments() Arguments {
 	return Arguments{
 		Unmodified:      false,
 		Untracked:       true,
-		Modified:        true,
+		Staged:          true,
+		Unstaged:        true,
+		Conflicted:      true,
 		StashedChanges:  true,
 		RemoteBehind:    true,
 		RemoteAhead:     true,
 		LocalOnlyBranch: true,
 
-		FetchType:  FetchNone,
-		FetchGroup: nil,
+		FetchType:   FetchNone,
+		FetchGroup:  nil,
+		FetchHost:   nil,
+		FetchPath:   nil,
+		FetchRemote: nil,
+		ProbeRemote: false,
+		FetchPrune:  false,
+		FetchTags:   FetchTagsFollow,
+
+		FetchTimeout:  2 * time.Minute,
+		FetchJobs:     8,
+		FetchHostJobs: 4,
+		FetchRetries:  2,
+
+		Backend: BackendGoGit,
+		Format:  FormatDefault,
+		Sort:    SortPath,
+
+		FilesLimit: 50,
 
 		Nested: false,
 	}
 }
+
+// PushArguments are options of the push subcommand
+type PushArguments struct {
+	// LocalOnlyBranch also pushes branches missing on remotes
+	LocalOnlyBranch bool
+	// Remote receives local only branches
+	Remote      string
+	SetUpstream bool
+	DryRun      bool
+	Interactive bool
+}
+
+// PullArguments are options of the pull subcommand
+type PullArguments struct {
+	DryRun bool
+}
+
+// RescueArguments are options of the rescue subcommand
+type RescueArguments struct {
+	// Out is the directory receiving rescued work
+	Out string
+}
+
+// TuiArguments are options of the tui subcommand
+type TuiArguments struct {
+	// Remote receives local only branches, as with push
+	Remote string
+}
+
+// SnapshotArguments are options of the snapshot subcommand
+type SnapshotArguments struct {
+	// Remote is the backup remote receiving snapshot refs, empty if refs are kept local
+	Remote string
+}
+
+// WatchArguments are options of the watch subcommand
+type WatchArguments struct {
+	// Debounce is how long events have to settle before changed repositories are checked
+	Debounce time.Duration
+	// Poll checks fingerprints of repositories instead of watching them with inotify
+	Poll bool
+	// PollInterval is the interval of polling, also used for repositories which cannot be watched
+	PollInterval time.Duration
+	// JSON prints a line of JSON for every changed repository instead of the summary
+	JSON bool
+}
+
+// DaemonArguments are options of the daemon subcommand
+type DaemonArguments struct {
+	// Socket is the path of the Unix socket answering queries
+	Socket string
+	// FetchInterval is the interval of fetching and checking every repository, 0 fetches only at start
+	FetchInterval time.Duration
+	Watch         WatchArguments
+}
+
+// QueryArguments are options of the query subcommand
+type QueryArguments struct {
+	Socket   string
+	Reporter *template.Template
+	Verbose  bool
+}
+
+// ServeArguments are options of the serve subcommand
+type ServeArguments struct {
+	// Listen is the address of the HTTP server
+	Listen string
+}
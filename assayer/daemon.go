@@ -0,0 +1,236 @@
+package assayer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/hov1417/assayer/arguments"
+	"github.com/hov1417/assayer/check"
+	"github.com/hov1417/assayer/types"
+)
+
+// queryTimeout bounds a query, so shell prompts do not hang on a stuck daemon
+const queryTimeout = 5 * time.Second
+
+// daemonRequest is a query sent to the daemon as a line of JSON
+type daemonRequest struct {
+	// Directories are absolute paths, repositories in them or containing them are answered
+	Directories []string `json:"directories"`
+}
+
+type daemonResponse struct {
+	Repositories []watchRecord `json:"repositories"`
+	// Values are the values of reporter templates
+	Values map[string]int `json:"values"`
+	Error  string         `json:"error,omitempty"`
+}
+
+// Daemon keeps verdicts of repositories up to date, watching them and fetching periodically,
+// and answers queries on the Unix socket until it is interrupted
+func Daemon(directories []string, args arguments.Arguments, daemonArgs arguments.DaemonArguments) error {
+	listener, err := listenSocket(daemonArgs.Socket)
+	if err != nil {
+		return err
+	}
+	defer listener.Close()
+
+	w, err := newWatchState(directories, args, daemonArgs.Watch)
+	if err != nil {
+		return err
+	}
+	defer w.close()
+	fetcher := newFetcherChecker(args)
+	defer fetcher.Scheduler.Finish()
+	defer fetcher.Auth.Finish()
+	assayer := check.NewAssayer(args, &fetcher)
+	repositoryPaths := slices.Collect(maps.Keys(w.repositories))
+	w.update(w.check(repositoryPaths, &assayer, &fetcher))
+	go w.serve(listener)
+
+	refreshes := make(chan []watchRecord)
+	done := make(chan struct{})
+	defer close(done)
+	if daemonArgs.FetchInterval > 0 {
+		go func() {
+			ticker := time.NewTicker(daemonArgs.FetchInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-done:
+					return
+				case <-ticker.C:
+				}
+				records := w.check(repositoryPaths, &assayer, &fetcher)
+				select {
+				case <-done:
+					return
+				case refreshes <- records:
+				}
+			}
+		}()
+	}
+	return w.loop(func([]watchRecord) {}, refreshes)
+}
+
+// listenSocket listens on the socket, replacing a socket left by a daemon which is not running
+func listenSocket(socket string) (net.Listener, error) {
+	err := os.MkdirAll(filepath.Dir(socket), 0o700)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create socket directory\n%s", err)
+	}
+	info, err := os.Lstat(socket)
+	if err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%s exists and is not a socket", socket)
+		}
+		connection, err := net.DialTimeout("unix", socket, time.Second)
+		if err == nil {
+			_ = connection.Close()
+			return nil, fmt.Errorf("assayer daemon is already running at %s", socket)
+		}
+		_ = os.Remove(socket)
+	}
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		return nil, fmt.Errorf("cannot listen on %s\n%s", socket, err)
+	}
+	err = os.Chmod(socket, 0o600)
+	if err != nil {
+		_ = listener.Close()
+		return nil, err
+	}
+	return listener, nil
+}
+
+func (w *watchState) serve(listener net.Listener) {
+	for {
+		connection, err := listener.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return
+		}
+		if err != nil {
+			continue
+		}
+		go w.answer(connection)
+	}
+}
+
+func (w *watchState) answer(connection net.Conn) {
+	defer connection.Close()
+	_ = connection.SetDeadline(time.Now().Add(queryTimeout))
+	var request daemonRequest
+	var response daemonResponse
+	err := json.NewDecoder(connection).Decode(&request)
+	if err != nil {
+		response.Error = fmt.Sprintf("invalid query: %s", err)
+	} else {
+		response = w.query(request)
+	}
+	_ = json.NewEncoder(connection).Encode(response)
+}
+
+// query answers records of repositories in or containing the requested directories
+func (w *watchState) query(request daemonRequest) daemonResponse {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+	response := daemonResponse{Repositories: []watchRecord{}}
+	var verdicts []types.Verdict
+	for _, record := range w.results {
+		if len(record.Verdicts) == 0 && record.Error == "" {
+			continue
+		}
+		repositoryPath, err := filepath.Abs(record.Path)
+		if err != nil {
+			continue
+		}
+		if len(request.Directories) > 0 && !slices.ContainsFunc(request.Directories, func(directory string) bool {
+			return within(repositoryPath, directory) || within(directory, repositoryPath)
+		}) {
+			continue
+		}
+		response.Repositories = append(response.Repositories, record)
+		verdicts = append(verdicts, record.verdicts...)
+	}
+	slices.SortFunc(response.Repositories, func(a, b watchRecord) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+
+	counts := newVerdictCounts()
+	for _, verdict := range verdicts {
+		counts.add(verdict)
+	}
+	response.Values = make(map[string]int)
+	for key, value := range counts.values() {
+		response.Values[key] = value.(int)
+	}
+	return response
+}
+
+// within reports whether path is the directory or inside it
+func within(path, directory string) bool {
+	relative, err := filepath.Rel(directory, path)
+	return err == nil && relative != ".." && !strings.HasPrefix(relative, ".."+string(filepath.Separator))
+}
+
+// Query asks the daemon for verdicts of repositories in or containing directories and reports them
+func Query(directories []string, queryArgs arguments.QueryArguments) error {
+	var request daemonRequest
+	for _, directory := range directories {
+		absolute, err := filepath.Abs(directory)
+		if err != nil {
+			return err
+		}
+		request.Directories = append(request.Directories, absolute)
+	}
+
+	connection, err := net.DialTimeout("unix", queryArgs.Socket, time.Second)
+	if err != nil {
+		return fmt.Errorf("cannot connect to assayer daemon at %s\n%s", queryArgs.Socket, err)
+	}
+	defer connection.Close()
+	_ = connection.SetDeadline(time.Now().Add(queryTimeout))
+	err = json.NewEncoder(connection).Encode(request)
+	if err != nil {
+		return fmt.Errorf("cannot send query\n%s", err)
+	}
+	var response daemonResponse
+	err = json.NewDecoder(connection).Decode(&response)
+	if err != nil {
+		return fmt.Errorf("cannot read answer of daemon\n%s", err)
+	}
+	if response.Error != "" {
+		return fmt.Errorf("daemon cannot answer query\n%s", response.Error)
+	}
+
+	if queryArgs.Reporter != nil {
+		values := make(map[string]any, len(response.Values))
+		for key, value := range response.Values {
+			values[key] = value
+		}
+		err = queryArgs.Reporter.Execute(os.Stdout, values)
+		if err != nil {
+			return fmt.Errorf("error executing template: %s", err)
+		}
+		return nil
+	}
+	for _, record := range response.Repositories {
+		if record.Error != "" {
+			_, _ = fmt.Fprintf(os.Stderr, "error checking %s: %s\n", record.Repository, record.Error)
+		}
+		for _, verdict := range record.Verdicts {
+			err = reportRepoResult(record.Repository, verdict.Kind, verdict.Details, queryArgs.Verbose)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package assayer
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/hov1417/assayer/cache"
+)
+
+// PrintIndex prints cached repositories of given roots, or of every indexed root if none are given
+func PrintIndex(roots []string) error {
+	index, err := cache.LoadIndex()
+	if err != nil {
+		return fmt.Errorf("error loading repository index\n%s", err)
+	}
+
+	var indexedRoots []string
+	if len(roots) == 0 {
+		for root := range index.Roots {
+			indexedRoots = append(indexedRoots, root)
+		}
+		slices.Sort(indexedRoots)
+	} else {
+		for _, root := range roots {
+			absRoot, err := filepath.Abs(root)
+			if err != nil {
+				return err
+			}
+			indexedRoots = append(indexedRoots, absRoot)
+		}
+	}
+
+	for _, root := range indexedRoots {
+		record, ok := index.Roots[root]
+		if !ok {
+			fmt.Printf("%s\n    not indexed\n", root)
+			continue
+		}
+		fmt.Printf(
+			"%s\n    scanned at %s, %d directories, %d repositories\n",
+			root,
+			record.ScannedAt.Format(time.DateTime),
+			len(record.Directories),
+			len(record.Repositories),
+		)
+		for _, repository := range record.Repositories {
+			fmt.Printf("    %s\n", repository)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package assayer
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+
+	"github.com/hov1417/assayer/arguments"
+	"github.com/hov1417/assayer/types"
+)
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Errors    int             `xml:"errors,attr"`
+	Timestamp string          `xml:"timestamp,attr,omitempty"`
+	Cases     []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string         `xml:"name,attr"`
+	ClassName string         `xml:"classname,attr"`
+	Failures  []junitProblem `xml:"failure"`
+	Errors    []junitProblem `xml:"error"`
+}
+
+type junitProblem struct {
+	Type    string `xml:"type,attr,omitempty"`
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// junitReporter writes a test suite per checked directory with a test case per repository,
+// every verdict of a repository is a failure of its test case
+type junitReporter struct {
+	*scanReporter
+	out io.Writer
+}
+
+func newJUnitReporter(out io.Writer, args arguments.Arguments) Reporter {
+	return &junitReporter{scanReporter: newScanReporter(args), out: out}
+}
+
+func (r *junitReporter) End() error {
+	err := r.scanReporter.End()
+	if err != nil {
+		return err
+	}
+	result := r.result
+
+	suites := make(map[string]*junitTestSuite, len(result.roots))
+	report := junitTestSuites{
+		Name: "assayer",
+		Time: fmt.Sprintf("%.3f", result.DurationSeconds),
+	}
+	for _, root := range result.roots {
+		report.Suites = append(report.Suites, junitTestSuite{
+			Name:      root,
+			Timestamp: result.StartedAt.Format("2006-01-02T15:04:05"),
+		})
+	}
+	for i := range report.Suites {
+		suites[report.Suites[i].Name] = &report.Suites[i]
+	}
+
+	// test cases of checked repositories, in order of their paths
+	cases := make(map[string]*junitTestCase, result.Checked)
+	var paths []string
+	caseOf := func(verdict types.Verdict) *junitTestCase {
+		fullPath := absolutePath(verdict)
+		if testCase, ok := cases[fullPath]; ok {
+			return testCase
+		}
+		root := result.rootOf(fullPath)
+		cases[fullPath] = &junitTestCase{
+			Name:      verdict.Repository(),
+			ClassName: filepath.Base(root),
+		}
+		paths = append(paths, fullPath)
+		return cases[fullPath]
+	}
+	for _, verdict := range r.checked {
+		caseOf(verdict)
+	}
+	for _, verdict := range result.verdicts {
+		testCase := caseOf(verdict)
+		kind, details := describeVerdict(verdict, r.files)
+		if kind == "" || kind == "Unmodified" {
+			continue
+		}
+		message := kind
+		if details != "" {
+			message = fmt.Sprintf("%s: %s", kind, details)
+		}
+		testCase.Failures = append(testCase.Failures, junitProblem{Type: kind, Message: message, Text: message})
+	}
+	slices.Sort(paths)
+	for _, fullPath := range paths {
+		testCase := cases[fullPath]
+		suite := suites[result.rootOf(fullPath)]
+		if suite == nil {
+			continue
+		}
+		suite.Cases = append(suite.Cases, *testCase)
+		suite.Tests += 1
+		if len(testCase.Failures) > 0 {
+			suite.Failures += 1
+		}
+	}
+
+	if len(result.Errors) > 0 {
+		errorSuite := junitTestSuite{Name: "errors"}
+		for i, checkError := range result.Errors {
+			errorSuite.Cases = append(errorSuite.Cases, junitTestCase{
+				Name:      fmt.Sprintf("error %d", i+1),
+				ClassName: "assayer",
+				Errors:    []junitProblem{{Message: checkError, Text: checkError}},
+			})
+		}
+		errorSuite.Tests = len(errorSuite.Cases)
+		errorSuite.Errors = len(errorSuite.Cases)
+		report.Suites = append(report.Suites, errorSuite)
+	}
+	for _, suite := range report.Suites {
+		report.Tests += suite.Tests
+		report.Failures += suite.Failures
+		report.Errors += suite.Errors
+	}
+
+	_, err = io.WriteString(r.out, xml.Header)
+	if err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(r.out)
+	encoder.Indent("", "  ")
+	err = encoder.Encode(report)
+	if err != nil {
+		return fmt.Errorf("cannot write JUnit report\n%s", err)
+	}
+	_, err = io.WriteString(r.out, "\n")
+	return err
+}
@@ -0,0 +1,134 @@
+package assayer
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/hov1417/assayer/arguments"
+	"github.com/hov1417/assayer/check"
+)
+
+// metricsContentType is the content type of the Prometheus text format
+const metricsContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// metricsReporter writes metrics in the Prometheus text format,
+// suitable for the textfile collector of node_exporter
+type metricsReporter struct {
+	*scanReporter
+	out  io.Writer
+	args arguments.Arguments
+}
+
+func newMetricsReporter(out io.Writer, args arguments.Arguments) Reporter {
+	return &metricsReporter{scanReporter: newScanReporter(args), out: out, args: args}
+}
+
+func (r *metricsReporter) End() error {
+	err := r.scanReporter.End()
+	if err != nil {
+		return err
+	}
+	return writeMetrics(r.out, r.result, r.args)
+}
+
+// checkedKinds are metric kinds of verdicts selected by arguments, they are reported even when zero
+func checkedKinds(args arguments.Arguments) []string {
+	var kinds []string
+	if args.Conflicted {
+		kinds = append(kinds, "conflicted")
+	}
+	if args.Staged {
+		kinds = append(kinds, "staged")
+	}
+	if args.Unstaged {
+		kinds = append(kinds, "unstaged")
+	}
+	if args.Untracked {
+		kinds = append(kinds, "untracked")
+	}
+	if args.StashedChanges {
+		kinds = append(kinds, "stashed_changes")
+	}
+	if args.LocalOnlyBranch {
+		kinds = append(kinds, "local_only_branch")
+	}
+	if args.RemoteAhead {
+		kinds = append(kinds, "remote_ahead")
+	}
+	if args.RemoteBehind {
+		kinds = append(kinds, "remote_behind")
+	}
+	if args.RemoteBehind && args.ProbeRemote {
+		kinds = append(kinds, "unpushed_tag")
+	}
+	return kinds
+}
+
+// metricKind returns the kind label of the verdict, e.g. local_only_branch
+func metricKind(kind string) string {
+	return strings.ReplaceAll(strings.ToLower(kind), " ", "_")
+}
+
+func writeMetrics(w io.Writer, result scanResult, args arguments.Arguments) error {
+	kinds := checkedKinds(args)
+	counts := make(map[string]map[string]int, len(result.roots))
+	for _, root := range result.roots {
+		counts[root] = make(map[string]int)
+	}
+	for _, verdict := range result.verdicts {
+		// more files only marks a truncated list of files
+		if _, moreFiles := verdict.(check.MoreFiles); moreFiles {
+			continue
+		}
+		kind, _ := describeVerdict(verdict, false)
+		if kind == "" {
+			continue
+		}
+		kind = metricKind(kind)
+		root := result.rootOf(absolutePath(verdict))
+		if counts[root] == nil {
+			counts[root] = make(map[string]int)
+		}
+		if counts[root][kind] == 0 && !slices.Contains(kinds, kind) {
+			kinds = append(kinds, kind)
+		}
+		counts[root][kind] += 1
+	}
+
+	out := bufio.NewWriter(w)
+	writeMetricHeader(out, "assayer_repositories_total", "Number of checked repositories.")
+	_, _ = fmt.Fprintf(out, "assayer_repositories_total %d\n", result.Checked)
+	writeMetricHeader(out, "assayer_repositories_with_findings", "Number of repositories with uncompleted work.")
+	_, _ = fmt.Fprintf(out, "assayer_repositories_with_findings %d\n", result.Repositories)
+	writeMetricHeader(out, "assayer_verdicts", "Number of verdicts by kind and checked directory.")
+	for _, root := range result.roots {
+		for _, kind := range kinds {
+			_, _ = fmt.Fprintf(
+				out,
+				"assayer_verdicts{kind=\"%s\",root=\"%s\"} %d\n",
+				escapeLabel(kind),
+				escapeLabel(root),
+				counts[root][kind],
+			)
+		}
+	}
+	writeMetricHeader(out, "assayer_check_errors_total", "Number of errors while checking repositories.")
+	_, _ = fmt.Fprintf(out, "assayer_check_errors_total %d\n", len(result.Errors))
+	writeMetricHeader(out, "assayer_scan_duration_seconds", "Duration of the last scan in seconds.")
+	_, _ = fmt.Fprintf(out, "assayer_scan_duration_seconds %g\n", result.DurationSeconds)
+	writeMetricHeader(out, "assayer_scan_timestamp_seconds", "Unix time of the start of the last scan.")
+	_, _ = fmt.Fprintf(out, "assayer_scan_timestamp_seconds %d\n", result.StartedAt.Unix())
+	return out.Flush()
+}
+
+func writeMetricHeader(w io.Writer, name, help string) {
+	_, _ = fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n", name, help, name)
+}
+
+// escapeLabel escapes the label value as the Prometheus text format requires
+func escapeLabel(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
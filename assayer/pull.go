@@ -0,0 +1,137 @@
+package assayer
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/hov1417/assayer/arguments"
+	"github.com/hov1417/assayer/check"
+	"github.com/hov1417/assayer/types"
+)
+
+// Pull fast-forwards branches whose remote is ahead, diverged branches and dirty worktrees are skipped
+func Pull(directories []string, args arguments.Arguments, pullArgs arguments.PullArguments) error {
+	verdicts, err := collectVerdicts(directories, args)
+	if err != nil {
+		return fmt.Errorf("error checking repositories\n%s", err)
+	}
+
+	backend := check.NewBackend(args.Backend)
+	detailed := len(directories) > 1
+	failed := 0
+	for _, verdict := range verdicts {
+		remoteAhead, ok := verdict.(check.RemoteAhead)
+		if !ok {
+			continue
+		}
+		repoName := types.RepoName(verdict, detailed)
+		target := fmt.Sprintf("%s <- %s", remoteAhead.LocalBranch(), remoteAhead.RemoteRefName())
+
+		skipReason, err := pullBranch(remoteAhead, backend, pullArgs.DryRun)
+		if err != nil {
+			failed += 1
+			err = reportRepoResult(
+				repoName,
+				"Pull Failed",
+				fmt.Sprintf("%s: %s", target, strings.ReplaceAll(err.Error(), "\n", ": ")),
+				true,
+			)
+		} else if skipReason != "" {
+			err = reportRepoResult(repoName, "Skipped", fmt.Sprintf("%s: %s", target, skipReason), true)
+		} else if pullArgs.DryRun {
+			err = reportRepoResult(repoName, "Would Pull", target, true)
+		} else {
+			err = reportRepoResult(repoName, "Pulled", target, true)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d branches failed to pull", failed)
+	}
+	return nil
+}
+
+// pullBranch fast-forwards the branch, it returns why the branch was skipped if it cannot be fast-forwarded
+func pullBranch(verdict check.RemoteAhead, backend check.Backend, dryRun bool) (string, error) {
+	repositoryPath := verdict.FullPath()
+	repo, err := git.PlainOpen(repositoryPath)
+	if err != nil {
+		return "", fmt.Errorf("error opening git repository\n%s", err)
+	}
+	localRef, err := repo.Reference(plumbing.NewBranchReferenceName(verdict.LocalBranch()), false)
+	if err != nil {
+		return "", fmt.Errorf("cannot find branch %s\n%s", verdict.LocalBranch(), err)
+	}
+	remoteRef, err := repo.Reference(plumbing.NewRemoteReferenceName(verdict.RemoteName(), verdict.RemoteBranch()), true)
+	if err != nil {
+		return "", fmt.Errorf("cannot find remote branch %s\n%s", verdict.RemoteRefName(), err)
+	}
+	if repo.Storer.HasEncodedObject(remoteRef.Hash()) != nil {
+		return "remote branch is not fetched", nil
+	}
+
+	fastForward, err := backend.IsAncestor(repositoryPath, repo, localRef.Hash(), remoteRef.Hash())
+	if err != nil {
+		return "", err
+	}
+	if !fastForward {
+		return "branch has diverged from remote", nil
+	}
+
+	head, err := repo.Reference(plumbing.HEAD, false)
+	if err != nil {
+		return "", fmt.Errorf("cannot read HEAD\n%s", err)
+	}
+	if head.Type() == plumbing.SymbolicReference && head.Target() == localRef.Name() {
+		status, err := backend.Status(repositoryPath, repo)
+		if err != nil {
+			return "", err
+		}
+		if !status.IsClean() {
+			return "worktree has uncommitted or untracked files", nil
+		}
+		if dryRun {
+			return "", nil
+		}
+		return "", backend.FastForwardHead(repositoryPath, repo, remoteRef.Hash())
+	}
+	// moving the branch of another worktree would leave its files behind as staged changes
+	worktree, err := worktreeOf(repositoryPath, localRef.Name())
+	if err != nil {
+		return "", err
+	}
+	if worktree != "" {
+		return fmt.Sprintf("branch is checked out in worktree %s", worktree), nil
+	}
+	if dryRun {
+		return "", nil
+	}
+	return "", backend.FastForwardBranch(
+		repositoryPath,
+		repo,
+		verdict.LocalBranch(),
+		localRef.Hash(),
+		remoteRef.Hash(),
+	)
+}
+
+// worktreeOf returns the path of the worktree which has the branch checked out, empty if there is none
+func worktreeOf(repositoryPath string, branch plumbing.ReferenceName) (string, error) {
+	output, err := check.RunGit(repositoryPath, "worktree", "list", "--porcelain")
+	if err != nil {
+		return "", err
+	}
+	worktree := ""
+	for _, line := range strings.Split(string(output), "\n") {
+		if path, found := strings.CutPrefix(line, "worktree "); found {
+			worktree = path
+		} else if line == "branch "+branch.String() {
+			return worktree, nil
+		}
+	}
+	return "", nil
+}
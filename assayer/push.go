@@ -0,0 +1,180 @@
+package assayer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/hov1417/assayer/arguments"
+	"github.com/hov1417/assayer/auth"
+	"github.com/hov1417/assayer/check"
+	"github.com/hov1417/assayer/types"
+)
+
+type branchPush struct {
+	branch       string
+	remote       string
+	remoteBranch string
+	setUpstream  bool
+}
+
+func (p branchPush) String() string {
+	return fmt.Sprintf("%s -> %s/%s", p.branch, p.remote, p.remoteBranch)
+}
+
+// Push pushes branches with commits not on their remote and, if asked, local only branches
+func Push(directories []string, args arguments.Arguments, pushArgs arguments.PushArguments) error {
+	verdicts, err := collectVerdicts(directories, args)
+	if err != nil {
+		return fmt.Errorf("error checking repositories\n%s", err)
+	}
+
+	provider := auth.NewProvider(args.SSHKeys)
+	defer provider.Finish()
+	detailed := len(directories) > 1
+	failed := 0
+	for _, verdict := range verdicts {
+		var push branchPush
+		switch verdict := verdict.(type) {
+		case check.RemoteBehind:
+			push = branchPush{
+				branch:       verdict.LocalBranch(),
+				remote:       verdict.RemoteName(),
+				remoteBranch: verdict.RemoteBranch(),
+			}
+		case check.LocalOnlyBranch:
+			push = branchPush{
+				branch:       verdict.BranchName(),
+				remote:       pushArgs.Remote,
+				remoteBranch: verdict.BranchName(),
+				setUpstream:  pushArgs.SetUpstream,
+			}
+		default:
+			continue
+		}
+
+		repoName := types.RepoName(verdict, detailed)
+		if pushArgs.DryRun {
+			err = reportRepoResult(repoName, "Would Push", push.String(), true)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		if pushArgs.Interactive {
+			confirmed, err := confirm(fmt.Sprintf("Push %s %s?", repoName, push))
+			if err != nil {
+				return err
+			}
+			if !confirmed {
+				err = reportRepoResult(repoName, "Skipped", push.String(), true)
+				if err != nil {
+					return err
+				}
+				continue
+			}
+		}
+
+		err = pushBranch(verdict.FullPath(), push, provider, args.FetchTimeout)
+		if err != nil {
+			failed += 1
+			err = reportRepoResult(
+				repoName,
+				"Push Failed",
+				fmt.Sprintf("%s: %s", push, strings.ReplaceAll(err.Error(), "\n", ": ")),
+				true,
+			)
+		} else {
+			err = reportRepoResult(repoName, "Pushed", push.String(), true)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d branches failed to push", failed)
+	}
+	return nil
+}
+
+func pushBranch(
+	repositoryPath string,
+	push branchPush,
+	provider *auth.Provider,
+	timeout time.Duration,
+) error {
+	repo, err := git.PlainOpen(repositoryPath)
+	if err != nil {
+		return fmt.Errorf("error opening git repository\n%s", err)
+	}
+	err = pushRefSpec(repo, push.remote, config.RefSpec(fmt.Sprintf(
+		"%s:%s",
+		plumbing.NewBranchReferenceName(push.branch),
+		plumbing.NewBranchReferenceName(push.remoteBranch),
+	)), provider, timeout)
+	if err != nil {
+		return err
+	}
+
+	if push.setUpstream {
+		repoConfig, err := repo.Config()
+		if err != nil {
+			return fmt.Errorf("error reading config\n%s", err)
+		}
+		repoConfig.Branches[push.branch] = &config.Branch{
+			Name:   push.branch,
+			Remote: push.remote,
+			Merge:  plumbing.NewBranchReferenceName(push.remoteBranch),
+		}
+		err = repo.SetConfig(repoConfig)
+		if err != nil {
+			return fmt.Errorf("error setting upstream\n%s", err)
+		}
+	}
+	return nil
+}
+
+// pushRefSpec pushes the refspec to the remote using the same credentials as fetch,
+// timeout is --fetch-timeout as pushes and fetches talk to the same remotes
+func pushRefSpec(
+	repo *git.Repository,
+	remoteName string,
+	refSpec config.RefSpec,
+	provider *auth.Provider,
+	timeout time.Duration,
+) error {
+	remote, err := repo.Remote(remoteName)
+	if err != nil {
+		return fmt.Errorf("cannot find remote %s\n%s", remoteName, err)
+	}
+	urls := remote.Config().URLs
+	if len(urls) == 0 {
+		return fmt.Errorf("remote %s has no url", remoteName)
+	}
+	// Push always uses first url of remote, as fetch does
+	pushAuth, err := provider.AuthFor(urls[0])
+	if err != nil {
+		return fmt.Errorf("error getting credentials for remote %s\n%s", remoteName, err)
+	}
+
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	err = repo.PushContext(ctx, &git.PushOptions{
+		RemoteName: remoteName,
+		RefSpecs:   []config.RefSpec{refSpec},
+		Auth:       pushAuth,
+	})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return err
+	}
+	return nil
+}
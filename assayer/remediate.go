@@ -0,0 +1,46 @@
+package assayer
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/hov1417/assayer/arguments"
+	"github.com/hov1417/assayer/types"
+)
+
+// collectVerdicts checks directories and returns verdicts ordered by repository path
+func collectVerdicts(directories []string, args arguments.Arguments) ([]types.Verdict, error) {
+	var collected []types.Verdict
+	err := CheckDirectories(directories, args, func(verdicts chan types.Response) error {
+		for verdictRecord := range verdicts {
+			if verdictRecord.Err != nil {
+				return verdictRecord.Err
+			}
+			collected = append(collected, verdictRecord.Verdict)
+		}
+		return nil
+	})
+	slices.SortStableFunc(collected, func(a, b types.Verdict) int {
+		return strings.Compare(a.FullPath(), b.FullPath())
+	})
+	return collected, err
+}
+
+var stdin = bufio.NewReader(os.Stdin)
+
+// confirm asks the question on stderr and reads the answer from stdin, no is the default
+func confirm(question string) (bool, error) {
+	_, err := fmt.Fprintf(os.Stderr, "%s [y/N] ", question)
+	if err != nil {
+		return false, err
+	}
+	answer, err := stdin.ReadString('\n')
+	if err != nil && answer == "" {
+		return false, fmt.Errorf("cannot read answer\n%s", err)
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes", nil
+}
@@ -2,6 +2,7 @@ package assayer
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"strings"
 
@@ -11,66 +12,72 @@ import (
 )
 
 func reportRepoResult(repo, verdictType, details string, verbose bool) error {
+	return writeRepoResult(os.Stdout, repo, verdictType, details, verbose)
+}
+
+func writeRepoResult(out io.Writer, repo, verdictType, details string, verbose bool) error {
 	var err error = nil
 	if verbose && details != "" {
-		_, err = fmt.Printf("%-60s %-40s %s\n", repo, verdictType, details)
+		_, err = fmt.Fprintf(out, "%-60s %-40s %s\n", repo, verdictType, details)
 	} else {
-		_, err = fmt.Printf("%-60s %s\n", repo, verdictType)
+		_, err = fmt.Fprintf(out, "%-60s %s\n", repo, verdictType)
 	}
 	return err
 }
 
-func ReportResults(verdicts chan types.Response, args arguments.Arguments, detailed bool) error {
-	for verdictRecord := range verdicts {
-		if verdictRecord.Err != nil {
-			return verdictRecord.Err
-		}
-		var err error = nil
-		switch verdict := verdictRecord.Verdict.(type) {
-		case types.Unmodified:
-			err = reportRepoResult(
-				types.RepoName(verdict, detailed),
-				"Unmodified",
-				"",
-				args.Verbose,
-			)
-		case check.Untracked:
-			err = reportRepoResult(types.RepoName(verdict, detailed),
-				"Untracked",
-				fmt.Sprintf("Path \"%s\" is untracked", verdict.UntrackedItem()),
-				args.Verbose)
-		case check.Modified:
-			err = reportRepoResult(
-				types.RepoName(verdict, detailed),
-				"Modified",
-				fmt.Sprintf(
-					"File \"%s\" is %s",
-					verdict.ModifiedItem(),
-					types.Stringify(verdict.ModificationType()),
-				),
-				args.Verbose,
-			)
-		case check.LocalOnlyBranch:
-			err = reportRepoResult(types.RepoName(verdict, detailed),
-				"Local Only Branch",
-				verdict.BranchName(),
-				args.Verbose)
-		case check.StashedChanges:
-			err = reportRepoResult(types.RepoName(verdict, detailed),
-				"Stashed Changes",
-				fmt.Sprintf("on commit \"%s\"", firstLine(verdict.CommitUnderStash().Message)),
-				args.Verbose)
-		case check.RemoteAhead:
-			err = reportRepoResult(types.RepoName(verdict, detailed),
-				"Remote Ahead",
-				verdict.LocalBranch(),
-				args.Verbose)
-		case check.RemoteBehind:
-			err = reportRepoResult(types.RepoName(verdict, detailed),
-				"Remote Behind",
-				verdict.LocalBranch(),
-				args.Verbose)
-		}
+// shown reports whether the verdict is shown, unmodified repositories are shown only when asked
+func shown(verdict types.Verdict, args arguments.Arguments) bool {
+	_, unmodified := verdict.(types.Unmodified)
+	return !unmodified || args.Unmodified
+}
+
+// defaultReporter prints a line per verdict, sorted at the end or as soon as it is found when streaming
+type defaultReporter struct {
+	out  io.Writer
+	args arguments.Arguments
+	// detailed names repositories with their checked directory, when several are checked
+	detailed bool
+	verdicts []types.Verdict
+}
+
+func newDefaultReporter(out io.Writer, args arguments.Arguments) Reporter {
+	return &defaultReporter{out: out, args: args}
+}
+
+func (r *defaultReporter) Begin(directories []string) error {
+	r.detailed = len(directories) > 1
+	return nil
+}
+
+func (r *defaultReporter) Verdict(verdict types.Verdict) error {
+	if !shown(verdict, r.args) {
+		return nil
+	}
+	if !r.args.Stream {
+		r.verdicts = append(r.verdicts, verdict)
+		return nil
+	}
+	return r.write(verdict)
+}
+
+func (r *defaultReporter) write(verdict types.Verdict) error {
+	verdictType, details := describeVerdict(verdict, r.args.Files)
+	if verdictType == "" {
+		return nil
+	}
+	// listing files is pointless without showing them
+	verbose := r.args.Verbose || r.args.Files
+	return writeRepoResult(r.out, types.RepoName(verdict, r.detailed), verdictType, details, verbose)
+}
+
+func (r *defaultReporter) Error(err error) error {
+	return err
+}
+
+func (r *defaultReporter) End() error {
+	sortVerdicts(r.verdicts, r.args.Sort, r.args.Files)
+	for _, verdict := range r.verdicts {
+		err := r.write(verdict)
 		if err != nil {
 			return err
 		}
@@ -78,98 +85,211 @@ func ReportResults(verdicts chan types.Response, args arguments.Arguments, detai
 	return nil
 }
 
-func ReportResultByCount(verdicts chan types.Response, arguments arguments.Arguments) error {
-	untracked := 0
-	modified := 0
-	localOnlyBranch := 0
-	stashedChanges := 0
-	remoteAhead := 0
-	remoteBehind := 0
-	for verdictRecord := range verdicts {
-		if verdictRecord.Err != nil {
-			return fmt.Errorf("checker error: %s", verdictRecord.Err)
-		}
-		switch verdictRecord.Verdict.(type) {
-		case check.Untracked:
-			untracked += 1
-		case check.Modified:
-			modified += 1
-		case check.LocalOnlyBranch:
-			localOnlyBranch += 1
-		case check.StashedChanges:
-			stashedChanges += 1
-		case check.RemoteAhead:
-			remoteAhead += 1
-		case check.RemoteBehind:
-			remoteBehind += 1
+// describeVerdict returns type and details of the verdict as shown in reports,
+// files formats file changes as `git status --short` does
+func describeVerdict(verdict types.Verdict, files bool) (string, string) {
+	switch verdict := verdict.(type) {
+	case types.Unmodified:
+		return "Unmodified", ""
+	case check.Untracked:
+		if files {
+			return "Untracked", fmt.Sprintf("?? %s", verdict.UntrackedItem())
 		}
+		return "Untracked", fmt.Sprintf("Path \"%s\" is untracked", verdict.UntrackedItem())
+	case check.Staged:
+		return "Staged", describeFileChange(verdict.FileChange, files)
+	case check.Unstaged:
+		return "Unstaged", describeFileChange(verdict.FileChange, files)
+	case check.Conflicted:
+		return "Conflicted", describeFileChange(verdict.FileChange, files)
+	case check.MoreFiles:
+		return "More Files", fmt.Sprintf("%d more files are not shown", verdict.Count())
+	case check.LocalOnlyBranch:
+		return "Local Only Branch", verdict.BranchName()
+	case check.StashedChanges:
+		return "Stashed Changes", fmt.Sprintf(
+			"on commit \"%s\"",
+			firstLine(verdict.CommitUnderStash().Message),
+		)
+	case check.RemoteAhead:
+		return "Remote Ahead", verdict.LocalBranch()
+	case check.RemoteBehind:
+		return "Remote Behind", verdict.LocalBranch()
+	case check.UnpushedTag:
+		return "Unpushed Tag", verdict.TagName()
 	}
-	if arguments.Untracked {
-		fmt.Printf("%-40s %d\n", "Repositories with Untracked files", untracked)
-	}
-	if arguments.Modified {
-		fmt.Printf("%-40s %d\n", "Modified Repositories", modified)
+	return "", ""
+}
+
+func describeFileChange(verdict check.FileChange, files bool) string {
+	if files {
+		return fileStatusLine(verdict)
 	}
-	if arguments.LocalOnlyBranch {
-		fmt.Printf("%-40s %d\n", "Repositories With Local Only Branches", localOnlyBranch)
+	return fmt.Sprintf(
+		"File \"%s\" is %s",
+		verdict.ModifiedItem(),
+		types.Stringify(verdict.ModificationType()),
+	)
+}
+
+// verdictCounts counts verdicts by kind
+type verdictCounts struct {
+	unmodified int
+	untracked  int
+	staged     int
+	unstaged   int
+	conflicted int
+	// repositories with any of staged, unstaged or conflicted changes
+	modified        map[string]bool
+	localOnlyBranch int
+	stashedChanges  int
+	remoteAhead     int
+	remoteBehind    int
+	unpushedTag     int
+}
+
+func newVerdictCounts() verdictCounts {
+	return verdictCounts{modified: make(map[string]bool)}
+}
+
+func (c *verdictCounts) add(verdict types.Verdict) {
+	switch verdict := verdict.(type) {
+	case types.Unmodified:
+		c.unmodified += 1
+	case check.Untracked:
+		c.untracked += 1
+	case check.Staged:
+		c.staged += 1
+		c.modified[verdict.RepositoryPath()] = true
+	case check.Unstaged:
+		c.unstaged += 1
+		c.modified[verdict.RepositoryPath()] = true
+	case check.Conflicted:
+		c.conflicted += 1
+		c.modified[verdict.RepositoryPath()] = true
+	case check.LocalOnlyBranch:
+		c.localOnlyBranch += 1
+	case check.StashedChanges:
+		c.stashedChanges += 1
+	case check.RemoteAhead:
+		c.remoteAhead += 1
+	case check.RemoteBehind:
+		c.remoteBehind += 1
+	case check.UnpushedTag:
+		c.unpushedTag += 1
 	}
-	if arguments.StashedChanges {
-		fmt.Printf("%-40s %d\n", "Repositories With Stashes", stashedChanges)
+}
+
+// values are the values of reporter templates
+func (c *verdictCounts) values() map[string]any {
+	values := make(map[string]any)
+	values["unmodified"] = c.unmodified
+	values["untracked"] = c.untracked
+	values["modified"] = len(c.modified)
+	values["staged"] = c.staged
+	values["unstaged"] = c.unstaged
+	values["conflicted"] = c.conflicted
+	values["localOnlyBranch"] = c.localOnlyBranch
+	values["stashedChanges"] = c.stashedChanges
+	values["remoteAhead"] = c.remoteAhead
+	values["remoteBehind"] = c.remoteBehind
+	values["unpushedTag"] = c.unpushedTag
+	return values
+}
+
+// countReporter prints the number of verdicts of every checked kind
+type countReporter struct {
+	out    io.Writer
+	args   arguments.Arguments
+	counts verdictCounts
+}
+
+func newCountReporter(out io.Writer, args arguments.Arguments) Reporter {
+	return &countReporter{out: out, args: args, counts: newVerdictCounts()}
+}
+
+func (r *countReporter) Begin([]string) error {
+	return nil
+}
+
+func (r *countReporter) Verdict(verdict types.Verdict) error {
+	r.counts.add(verdict)
+	return nil
+}
+
+func (r *countReporter) Error(err error) error {
+	return fmt.Errorf("checker error: %s", err)
+}
+
+func (r *countReporter) End() error {
+	lines := []struct {
+		checked bool
+		title   string
+		count   int
+	}{
+		{r.args.Untracked, "Repositories with Untracked files", r.counts.untracked},
+		{r.args.Staged, "Repositories With Staged Changes", r.counts.staged},
+		{r.args.Unstaged, "Repositories With Unstaged Changes", r.counts.unstaged},
+		{r.args.Conflicted, "Repositories With Merge Conflicts", r.counts.conflicted},
+		{r.args.LocalOnlyBranch, "Repositories With Local Only Branches", r.counts.localOnlyBranch},
+		{r.args.StashedChanges, "Repositories With Stashes", r.counts.stashedChanges},
+		{r.args.RemoteAhead, "Not Pulled Repositories", r.counts.remoteAhead},
+		{r.args.RemoteBehind, "Not Pushed Repositories", r.counts.remoteBehind},
+		{r.args.RemoteBehind && r.args.ProbeRemote, "Repositories With Unpushed Tags", r.counts.unpushedTag},
 	}
-	if arguments.RemoteAhead {
-		fmt.Printf("%-40s %d\n", "Not Pulled Repositories", remoteAhead)
+	for _, line := range lines {
+		if !line.checked {
+			continue
+		}
+		_, err := fmt.Fprintf(r.out, "%-40s %d\n", line.title, line.count)
+		if err != nil {
+			return err
+		}
 	}
-	if arguments.RemoteBehind {
-		fmt.Printf("%-40s %d\n", "Not Pushed Repositories", remoteBehind)
+	return nil
+}
+
+// templateReporter executes the reporter template with counts of verdicts
+type templateReporter struct {
+	*countReporter
+}
+
+func newTemplateReporter(out io.Writer, args arguments.Arguments) Reporter {
+	return &templateReporter{&countReporter{out: out, args: args, counts: newVerdictCounts()}}
+}
+
+func (r *templateReporter) Verdict(verdict types.Verdict) error {
+	if shown(verdict, r.args) {
+		r.counts.add(verdict)
 	}
 	return nil
 }
 
-func ReportResultWithReporter(verdicts chan types.Response, arguments arguments.Arguments) error {
-	unmodified := 0
-	untracked := 0
-	modified := 0
-	localOnlyBranch := 0
-	stashedChanges := 0
-	remoteAhead := 0
-	remoteBehind := 0
-	for verdictRecord := range verdicts {
-		if verdictRecord.Err != nil {
-			return fmt.Errorf("checker error: %s", verdictRecord.Err)
-		}
-		switch verdictRecord.Verdict.(type) {
-		case types.Unmodified:
-			unmodified += 1
-		case check.Untracked:
-			untracked += 1
-		case check.Modified:
-			modified += 1
-		case check.LocalOnlyBranch:
-			localOnlyBranch += 1
-		case check.StashedChanges:
-			stashedChanges += 1
-		case check.RemoteAhead:
-			remoteAhead += 1
-		case check.RemoteBehind:
-			remoteBehind += 1
-		}
+func (r *templateReporter) End() error {
+	if r.args.Reporter == nil {
+		return fmt.Errorf("template format requires --reporter flag")
 	}
-	values := make(map[string]any)
-	values["unmodified"] = unmodified
-	values["untracked"] = untracked
-	values["modified"] = modified
-	values["localOnlyBranch"] = localOnlyBranch
-	values["stashedChanges"] = stashedChanges
-	values["remoteAhead"] = remoteAhead
-	values["remoteBehind"] = remoteBehind
-
-	err := arguments.Reporter.Execute(os.Stdout, values)
+	err := r.args.Reporter.Execute(r.out, r.counts.values())
 	if err != nil {
 		return fmt.Errorf("error executing template: %s", err)
 	}
 	return nil
 }
 
+// fileStatusLine formats the file like `git status --short` does
+func fileStatusLine(verdict check.FileChange) string {
+	if verdict.OriginalItem() != "" {
+		return fmt.Sprintf(
+			"%c%c %s -> %s",
+			verdict.Staging(),
+			verdict.Worktree(),
+			verdict.OriginalItem(),
+			verdict.ModifiedItem(),
+		)
+	}
+	return fmt.Sprintf("%c%c %s", verdict.Staging(), verdict.Worktree(), verdict.ModifiedItem())
+}
+
 func firstLine(message string) string {
 	newline := strings.IndexFunc(message, func(char rune) bool {
 		return char == '\n' || char == '\r'
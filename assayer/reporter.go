@@ -0,0 +1,85 @@
+package assayer
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/hov1417/assayer/arguments"
+	"github.com/hov1417/assayer/types"
+)
+
+// Reporter reports verdicts of checked repositories in a format selected by --format
+type Reporter interface {
+	// Begin is called once before any verdict with the checked directories
+	Begin(directories []string) error
+	// Verdict is called for every verdict, unmodified repositories included
+	Verdict(verdict types.Verdict) error
+	// Error is called for every failed check, returning an error stops checking
+	Error(err error) error
+	// End is called once after all verdicts
+	End() error
+}
+
+// newReporterFunc creates a reporter writing to out
+type newReporterFunc func(out io.Writer, args arguments.Arguments) Reporter
+
+// reporters are constructors of reporters by format
+var reporters = map[string]newReporterFunc{
+	arguments.FormatDefault: newDefaultReporter,
+	"count":                 newCountReporter,
+	"template":              newTemplateReporter,
+	"table":                 newTableReporter,
+	"tree":                  newTreeReporter,
+	"prometheus":            newMetricsReporter,
+	"junit":                 newJUnitReporter,
+	"sarif":                 newSarifReporter,
+}
+
+// NewReporter creates the reporter of the format writing to out
+func NewReporter(format string, out io.Writer, args arguments.Arguments) (Reporter, error) {
+	newReporter, ok := reporters[format]
+	if !ok {
+		return nil, fmt.Errorf(
+			"unknown format \"%s\", expected one of %s",
+			format,
+			strings.Join(Formats(), ", "),
+		)
+	}
+	return newReporter(out, args), nil
+}
+
+// Formats returns names of registered formats
+func Formats() []string {
+	return slices.Sorted(maps.Keys(reporters))
+}
+
+// Report checks repositories of directories and passes their verdicts to the reporter
+func Report(directories []string, args arguments.Arguments, reporter Reporter) error {
+	err := reporter.Begin(directories)
+	if err != nil {
+		return err
+	}
+	// reporters are told about every checked repository, they know from their arguments what to show
+	args.Unmodified = true
+	err = CheckDirectories(directories, args, func(verdicts chan types.Response) error {
+		for verdictRecord := range verdicts {
+			var err error
+			if verdictRecord.Err != nil {
+				err = reporter.Error(verdictRecord.Err)
+			} else {
+				err = reporter.Verdict(verdictRecord.Verdict)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return reporter.End()
+}
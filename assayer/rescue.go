@@ -0,0 +1,411 @@
+package assayer
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/hov1417/assayer/arguments"
+	"github.com/hov1417/assayer/check"
+	"github.com/hov1417/assayer/types"
+)
+
+// emptyTree is the hash of the empty tree, used to diff repositories without commits
+const emptyTree = "4b825dc642cb6eb9a060e54bf8d69288fbee4904"
+
+// rescueRefs is where stash and lost commits are referenced in bundles
+const rescueRefs = "refs/assayer/rescue/"
+
+type rescueManifest struct {
+	CreatedAt time.Time        `json:"createdAt"`
+	Artifacts []rescueArtifact `json:"artifacts"`
+}
+
+// rescueArtifact is a file written for a repository, with verdicts of the work it keeps
+type rescueArtifact struct {
+	File       string   `json:"file"`
+	Kind       string   `json:"kind"`
+	Repository string   `json:"repository"`
+	Path       string   `json:"path"`
+	Verdicts   []string `json:"verdicts"`
+	// Refs are names of bundled refs
+	Refs []string `json:"refs,omitempty"`
+}
+
+// rescueRepository is the unfinished work of a repository
+type rescueRepository struct {
+	path           string
+	repositoryPath string
+	name           string
+	branches       []string
+	tags           []string
+	bundled        []string
+	changed        []string
+	untracked      []string
+}
+
+// Rescue writes bundles of unpushed commits, patches of uncommitted changes and tars of untracked files
+// of every repository with findings into out directory, together with manifest.json describing them
+func Rescue(directories []string, args arguments.Arguments, rescueArgs arguments.RescueArguments) error {
+	verdicts, err := collectVerdicts(directories, args)
+	if err != nil {
+		return fmt.Errorf("error checking repositories\n%s", err)
+	}
+
+	roots, err := rescueRoots(directories)
+	if err != nil {
+		return err
+	}
+	detailed := len(directories) > 1
+	var repositories []*rescueRepository
+	for _, verdict := range verdicts {
+		fullPath, err := filepath.Abs(verdict.FullPath())
+		if err != nil {
+			return err
+		}
+		if len(repositories) == 0 || repositories[len(repositories)-1].path != fullPath {
+			repositoryPath := verdict.RepositoryPath()
+			for root, name := range roots {
+				if filepath.Join(root, verdict.Repository()) == fullPath {
+					repositoryPath = path.Join(name, filepath.ToSlash(verdict.Repository()))
+				}
+			}
+			repositories = append(repositories, &rescueRepository{
+				path:           fullPath,
+				repositoryPath: repositoryPath,
+				name:           types.RepoName(verdict, detailed),
+			})
+		}
+		repository := repositories[len(repositories)-1]
+		verdictType, details := describeVerdict(verdict, true)
+		description := strings.TrimSpace(verdictType + " " + details)
+		switch verdict := verdict.(type) {
+		case check.RemoteBehind:
+			repository.branches = append(repository.branches, verdict.LocalBranch())
+			repository.bundled = append(repository.bundled, description)
+		case check.LocalOnlyBranch:
+			repository.branches = append(repository.branches, verdict.BranchName())
+			repository.bundled = append(repository.bundled, description)
+		case check.UnpushedTag:
+			repository.tags = append(repository.tags, verdict.TagName())
+			repository.bundled = append(repository.bundled, description)
+		case check.StashedChanges:
+			repository.bundled = append(repository.bundled, description)
+		case check.Staged, check.Unstaged, check.Conflicted:
+			repository.changed = append(repository.changed, description)
+		case check.Untracked:
+			repository.untracked = append(repository.untracked, description)
+		}
+	}
+
+	// git runs in repositories, so it needs an absolute path
+	out, err := filepath.Abs(rescueArgs.Out)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(out, 0o755)
+	if err != nil {
+		return fmt.Errorf("cannot create rescue directory\n%s", err)
+	}
+	manifest := rescueManifest{CreatedAt: time.Now(), Artifacts: []rescueArtifact{}}
+	for _, repository := range repositories {
+		artifacts, err := repository.rescue(out)
+		if err != nil {
+			return fmt.Errorf("error rescuing %s\n%s", repository.repositoryPath, err)
+		}
+		for _, artifact := range artifacts {
+			err = reportRepoResult(repository.name, "Rescued", artifact.File, true)
+			if err != nil {
+				return err
+			}
+		}
+		manifest.Artifacts = append(manifest.Artifacts, artifacts...)
+	}
+
+	content, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(filepath.Join(out, "manifest.json"), content, 0o644)
+	if err != nil {
+		return fmt.Errorf("cannot write manifest\n%s", err)
+	}
+	return nil
+}
+
+// rescueRoots names directories of rescued repositories by checked directories, directories with the same
+// base name are told apart by their position in arguments, e.g. src and src-2
+func rescueRoots(directories []string) (map[string]string, error) {
+	roots := make(map[string]string, len(directories))
+	names := make(map[string]bool, len(directories))
+	for i, directory := range directories {
+		root, err := filepath.Abs(directory)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := roots[root]; ok {
+			continue
+		}
+		name := filepath.Base(root)
+		for position := i + 1; names[name]; position++ {
+			name = fmt.Sprintf("%s-%d", filepath.Base(root), position)
+		}
+		names[name] = true
+		roots[root] = name
+	}
+	return roots, nil
+}
+
+func (r *rescueRepository) rescue(out string) ([]rescueArtifact, error) {
+	directory := filepath.Join(out, filepath.FromSlash(r.repositoryPath))
+	err := os.MkdirAll(filepath.Dir(directory), 0o755)
+	if err != nil {
+		return nil, err
+	}
+	// artifacts of another repository, or of an earlier rescue, are never overwritten
+	err = os.Mkdir(directory, 0o755)
+	if errors.Is(err, fs.ErrExist) {
+		return nil, fmt.Errorf("rescue directory %s already exists", directory)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var artifacts []rescueArtifact
+	newArtifact := func(file, kind string, verdicts []string) rescueArtifact {
+		return rescueArtifact{
+			File:       filepath.ToSlash(filepath.Join(r.repositoryPath, file)),
+			Kind:       kind,
+			Repository: r.repositoryPath,
+			Path:       r.path,
+			Verdicts:   verdicts,
+		}
+	}
+
+	refs, err := r.bundle(filepath.Join(directory, "commits.bundle"))
+	if err != nil {
+		return nil, err
+	}
+	if len(refs) > 0 {
+		artifact := newArtifact("commits.bundle", "bundle", r.bundled)
+		artifact.Refs = refs
+		artifacts = append(artifacts, artifact)
+	}
+
+	if len(r.changed) > 0 {
+		err = r.patch(filepath.Join(directory, "changes.patch"))
+		if err != nil {
+			return nil, err
+		}
+		artifacts = append(artifacts, newArtifact("changes.patch", "patch", r.changed))
+	}
+
+	if len(r.untracked) > 0 {
+		err = r.tarUntracked(filepath.Join(directory, "untracked.tar"))
+		if err != nil {
+			return nil, err
+		}
+		artifacts = append(artifacts, newArtifact("untracked.tar", "tar", r.untracked))
+	}
+	return artifacts, nil
+}
+
+// bundle writes unpushed branches and tags, stashes and commits only referenced by reflogs into file,
+// it returns names of bundled refs
+func (r *rescueRepository) bundle(file string) ([]string, error) {
+	var names []string
+	for _, branch := range r.branches {
+		names = append(names, "refs/heads/"+branch)
+	}
+	for _, tag := range r.tags {
+		names = append(names, "refs/tags/"+tag)
+	}
+	refs := make(map[string]string)
+	if len(names) > 0 {
+		output, err := check.RunGit(r.path, append([]string{"rev-parse"}, names...)...)
+		if err != nil {
+			return nil, err
+		}
+		for i, hash := range strings.Fields(string(output)) {
+			refs[names[i]] = hash
+		}
+	}
+
+	// stashes and lost commits have no refs of their own, bundles can only contain refs
+	output, err := check.RunGit(r.path, "stash", "list", "--format=%H")
+	if err != nil {
+		return nil, err
+	}
+	stashes := strings.Fields(string(output))
+	for i, hash := range stashes {
+		refs[fmt.Sprintf("%sstash/%d", rescueRefs, i)] = hash
+	}
+	lost, err := r.lostCommits()
+	if err != nil {
+		return nil, err
+	}
+	for _, hash := range lost {
+		// older stashes are only in the stash reflog, they are bundled as stashes
+		if !slices.Contains(stashes, hash) {
+			refs[rescueRefs+"lost/"+hash] = hash
+		}
+	}
+
+	if len(refs) == 0 {
+		return nil, nil
+	}
+	err = r.bundleRefs(file, refs)
+	if err != nil {
+		return nil, err
+	}
+	return slices.Sorted(maps.Keys(refs)), nil
+}
+
+// bundleRefs creates the bundle of refs in a temporary repository which borrows objects of the repository
+// through alternates, so no refs are written into the repository
+func (r *rescueRepository) bundleRefs(file string, refs map[string]string) error {
+	output, err := check.RunGit(r.path, "rev-parse", "--path-format=absolute", "--git-path", "objects")
+	if err != nil {
+		return err
+	}
+	objects := strings.TrimSpace(string(output))
+	output, err = check.RunGit(r.path, "rev-parse", "--show-object-format")
+	if err != nil {
+		return err
+	}
+	objectFormat := strings.TrimSpace(string(output))
+
+	temporary, err := os.MkdirTemp("", "assayer-rescue-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(temporary)
+	_, err = check.RunGit(temporary, "init", "--quiet", "--bare", "--object-format="+objectFormat)
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(filepath.Join(temporary, "objects", "info", "alternates"), []byte(objects+"\n"), 0o644)
+	if err != nil {
+		return err
+	}
+	names := slices.Sorted(maps.Keys(refs))
+	var packedRefs strings.Builder
+	for _, name := range names {
+		packedRefs.WriteString(refs[name] + " " + name + "\n")
+	}
+	err = os.WriteFile(filepath.Join(temporary, "packed-refs"), []byte(packedRefs.String()), 0o644)
+	if err != nil {
+		return err
+	}
+	_, err = check.RunGit(temporary, append([]string{"bundle", "create", "--quiet", file}, names...)...)
+	return err
+}
+
+// lostCommits are tips of commits of reflogs which are not reachable from any ref,
+// commits reachable from other lost commits are left out
+func (r *rescueRepository) lostCommits() ([]string, error) {
+	output, err := check.RunGit(r.path, "reflog", "--all", "--format=%H")
+	if err != nil {
+		return nil, err
+	}
+	hashes := slices.Compact(slices.Sorted(slices.Values(strings.Fields(string(output)))))
+	if len(hashes) == 0 {
+		return nil, nil
+	}
+	args := append([]string{"rev-list", "--ignore-missing"}, hashes...)
+	output, err = check.RunGit(r.path, append(args, "--not", "--all")...)
+	if err != nil {
+		return nil, err
+	}
+	lost := strings.Fields(string(output))
+	if len(lost) < 2 {
+		return lost, nil
+	}
+	output, err = check.RunGit(r.path, append([]string{"merge-base", "--independent"}, lost...)...)
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(string(output)), nil
+}
+
+// patch writes staged and unstaged changes of tracked files, relative to HEAD
+func (r *rescueRepository) patch(file string) error {
+	base := "HEAD"
+	_, err := check.RunGit(r.path, "rev-parse", "--verify", "--quiet", "HEAD")
+	if err != nil {
+		base = emptyTree
+	}
+	output, err := check.RunGit(r.path, "diff", "--binary", base)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(file, output, 0o644)
+}
+
+// tarUntracked writes untracked files, which are not ignored, into a tar archive
+func (r *rescueRepository) tarUntracked(file string) error {
+	output, err := check.RunGit(r.path, "ls-files", "--others", "--exclude-standard", "-z")
+	if err != nil {
+		return err
+	}
+	var buffer bytes.Buffer
+	archive := tar.NewWriter(&buffer)
+	for _, item := range strings.Split(strings.TrimSuffix(string(output), "\x00"), "\x00") {
+		if item == "" {
+			continue
+		}
+		err = addToTar(archive, r.path, item)
+		if err != nil {
+			return err
+		}
+	}
+	err = archive.Close()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(file, buffer.Bytes(), 0o644)
+}
+
+func addToTar(archive *tar.Writer, repositoryPath, item string) error {
+	fullPath := filepath.Join(repositoryPath, filepath.FromSlash(item))
+	info, err := os.Lstat(fullPath)
+	if err != nil {
+		return err
+	}
+	link := ""
+	if info.Mode()&os.ModeSymlink != 0 {
+		link, err = os.Readlink(fullPath)
+		if err != nil {
+			return err
+		}
+	}
+	header, err := tar.FileInfoHeader(info, link)
+	if err != nil {
+		return err
+	}
+	header.Name = item
+	err = archive.WriteHeader(header)
+	if err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return nil
+	}
+	content, err := os.Open(fullPath)
+	if err != nil {
+		return err
+	}
+	defer content.Close()
+	_, err = io.Copy(archive, content)
+	return err
+}
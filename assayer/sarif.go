@@ -0,0 +1,206 @@
+package assayer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"path/filepath"
+
+	"github.com/hov1417/assayer/arguments"
+	"github.com/hov1417/assayer/check"
+	"github.com/hov1417/assayer/types"
+)
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool               sarifTool                    `json:"tool"`
+	OriginalURIBaseIDs map[string]sarifArtifactPath `json:"originalUriBaseIds,omitempty"`
+	Invocations        []sarifInvocation            `json:"invocations"`
+	Results            []sarifResult                `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	Name             string       `json:"name"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifInvocation struct {
+	ExecutionSuccessful bool                `json:"executionSuccessful"`
+	Notifications       []sarifNotification `json:"toolExecutionNotifications,omitempty"`
+}
+
+type sarifNotification struct {
+	Level   string       `json:"level"`
+	Message sarifMessage `json:"message"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactPath `json:"artifactLocation"`
+}
+
+type sarifArtifactPath struct {
+	URI       string `json:"uri"`
+	URIBaseID string `json:"uriBaseId,omitempty"`
+}
+
+// sarifRules describe verdict kinds, their ids are the kinds of metrics
+var sarifRules = map[string]string{
+	"conflicted":        "Unresolved merge conflict",
+	"staged":            "Change staged but not committed",
+	"unstaged":          "Change not added to the index",
+	"untracked":         "Untracked file",
+	"more_files":        "Files not listed because of --files-limit",
+	"stashed_changes":   "Stashed changes",
+	"local_only_branch": "Branch missing on remotes",
+	"remote_ahead":      "Branch behind its remote",
+	"remote_behind":     "Branch with commits not pushed",
+	"unpushed_tag":      "Tag missing on remotes",
+}
+
+// sarifReporter writes a SARIF log with a result per verdict, located at the changed file
+// or at the repository for verdicts which are not about files
+type sarifReporter struct {
+	*scanReporter
+	out io.Writer
+}
+
+func newSarifReporter(out io.Writer, args arguments.Arguments) Reporter {
+	return &sarifReporter{scanReporter: newScanReporter(args), out: out}
+}
+
+func (r *sarifReporter) End() error {
+	err := r.scanReporter.End()
+	if err != nil {
+		return err
+	}
+	result := r.result
+
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           "assayer",
+			InformationURI: "https://github.com/hov1417/assayer",
+			Rules:          []sarifRule{},
+		}},
+		OriginalURIBaseIDs: make(map[string]sarifArtifactPath, len(result.roots)),
+		Invocations:        []sarifInvocation{{ExecutionSuccessful: len(result.Errors) == 0}},
+		Results:            []sarifResult{},
+	}
+	// paths are relative to the checked directory, each has its own base
+	baseIDs := make(map[string]string, len(result.roots))
+	for i, root := range result.roots {
+		baseID := fmt.Sprintf("ROOT%d", i)
+		baseIDs[root] = baseID
+		run.OriginalURIBaseIDs[baseID] = sarifArtifactPath{URI: fileURI(root) + "/"}
+	}
+	for _, checkError := range result.Errors {
+		run.Invocations[0].Notifications = append(run.Invocations[0].Notifications, sarifNotification{
+			Level:   "error",
+			Message: sarifMessage{Text: checkError},
+		})
+	}
+
+	rules := make(map[string]bool)
+	for _, verdict := range result.verdicts {
+		kind, details := describeVerdict(verdict, r.files)
+		if kind == "" || kind == "Unmodified" {
+			continue
+		}
+		ruleID := metricKind(kind)
+		if !rules[ruleID] {
+			rules[ruleID] = true
+			description, ok := sarifRules[ruleID]
+			if !ok {
+				description = kind
+			}
+			run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{
+				ID:               ruleID,
+				Name:             kind,
+				ShortDescription: sarifMessage{Text: description},
+			})
+		}
+
+		fullPath := absolutePath(verdict)
+		root := result.rootOf(fullPath)
+		location := sarifArtifactPath{URI: fileURI(fullPath) + "/"}
+		if repository, err := filepath.Rel(root, fullPath); root != "" && err == nil {
+			location = sarifArtifactPath{URI: relativeURI(filepath.Join(repository, verdictFile(verdict))), URIBaseID: baseIDs[root]}
+		}
+		message := fmt.Sprintf("%s in %s", kind, verdict.Repository())
+		if details != "" {
+			message = fmt.Sprintf("%s in %s: %s", kind, verdict.Repository(), details)
+		}
+		run.Results = append(run.Results, sarifResult{
+			RuleID:    ruleID,
+			Level:     "warning",
+			Message:   sarifMessage{Text: message},
+			Locations: []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{ArtifactLocation: location}}},
+		})
+	}
+
+	encoder := json.NewEncoder(r.out)
+	encoder.SetIndent("", "  ")
+	err = encoder.Encode(sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{run},
+	})
+	if err != nil {
+		return fmt.Errorf("cannot write SARIF report\n%s", err)
+	}
+	return nil
+}
+
+// verdictFile returns the file of the verdict relative to its repository, empty for verdicts not about files
+func verdictFile(verdict types.Verdict) string {
+	switch verdict := verdict.(type) {
+	case check.Untracked:
+		return verdict.UntrackedItem()
+	case check.Staged:
+		return verdict.ModifiedItem()
+	case check.Unstaged:
+		return verdict.ModifiedItem()
+	case check.Conflicted:
+		return verdict.ModifiedItem()
+	}
+	return ""
+}
+
+func fileURI(path string) string {
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+}
+
+func relativeURI(path string) string {
+	return (&url.URL{Path: filepath.ToSlash(path)}).String()
+}
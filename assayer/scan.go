@@ -0,0 +1,110 @@
+package assayer
+
+import (
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/hov1417/assayer/arguments"
+	"github.com/hov1417/assayer/types"
+)
+
+// scanResult is the result of checking all repositories of directories
+type scanResult struct {
+	StartedAt       time.Time `json:"startedAt"`
+	DurationSeconds float64   `json:"durationSeconds"`
+	// Checked is the number of checked repositories, Repositories the number of those with findings
+	Checked      int      `json:"checked"`
+	Repositories int      `json:"repositories"`
+	Verdicts     int      `json:"verdicts"`
+	Errors       []string `json:"errors"`
+	verdicts     []types.Verdict
+	roots        []string
+}
+
+// scanReporter collects verdicts of a scan, sorted in the order of arguments
+type scanReporter struct {
+	result           scanResult
+	reportUnmodified bool
+	files            bool
+	order            arguments.SortOrder
+	// checked are the first verdicts of checked repositories by their paths
+	checked      map[string]types.Verdict
+	withFindings map[string]bool
+}
+
+func newScanReporter(args arguments.Arguments) *scanReporter {
+	return &scanReporter{
+		result:           scanResult{Errors: []string{}},
+		reportUnmodified: args.Unmodified,
+		files:            args.Files,
+		order:            args.Sort,
+		checked:          make(map[string]types.Verdict),
+		withFindings:     make(map[string]bool),
+	}
+}
+
+// scanDirectories checks repositories of directories, errors of repositories are recorded instead of stopping the scan
+func scanDirectories(directories []string, args arguments.Arguments) (scanResult, error) {
+	reporter := newScanReporter(args)
+	err := Report(directories, args, reporter)
+	return reporter.result, err
+}
+
+func (r *scanReporter) Begin(directories []string) error {
+	r.result.StartedAt = time.Now()
+	for _, directory := range directories {
+		root, err := filepath.Abs(directory)
+		if err != nil {
+			return err
+		}
+		r.result.roots = append(r.result.roots, root)
+	}
+	return nil
+}
+
+func (r *scanReporter) Verdict(verdict types.Verdict) error {
+	if _, ok := r.checked[verdict.FullPath()]; !ok {
+		r.checked[verdict.FullPath()] = verdict
+	}
+	if _, unmodified := verdict.(types.Unmodified); unmodified && !r.reportUnmodified {
+		return nil
+	}
+	r.withFindings[verdict.FullPath()] = true
+	r.result.verdicts = append(r.result.verdicts, verdict)
+	return nil
+}
+
+func (r *scanReporter) Error(err error) error {
+	r.result.Errors = append(r.result.Errors, strings.ReplaceAll(err.Error(), "\n", ": "))
+	return nil
+}
+
+func (r *scanReporter) End() error {
+	sortVerdicts(r.result.verdicts, r.order, r.files)
+	r.result.DurationSeconds = time.Since(r.result.StartedAt).Seconds()
+	r.result.Checked = len(r.checked)
+	r.result.Repositories = len(r.withFindings)
+	r.result.Verdicts = len(r.result.verdicts)
+	return nil
+}
+
+// rootOf returns the checked directory containing the repository
+func (scan scanResult) rootOf(fullPath string) string {
+	root := ""
+	for _, directory := range scan.roots {
+		if within(fullPath, directory) && len(directory) > len(root) {
+			root = directory
+		}
+	}
+	return root
+}
+
+// absolutePath returns the absolute path of the repository of the verdict
+func absolutePath(verdict types.Verdict) string {
+	fullPath, err := filepath.Abs(verdict.FullPath())
+	if err != nil {
+		return verdict.FullPath()
+	}
+	return fullPath
+}
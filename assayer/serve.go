@@ -0,0 +1,248 @@
+package assayer
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/hov1417/assayer/arguments"
+)
+
+//go:embed dashboard.html
+var dashboard []byte
+
+type serverVerdict struct {
+	Root       string `json:"root"`
+	Repository string `json:"repository"`
+	Path       string `json:"path"`
+	Kind       string `json:"kind"`
+	Details    string `json:"details,omitempty"`
+}
+
+type serverRepository struct {
+	Root       string         `json:"root"`
+	Repository string         `json:"repository"`
+	Path       string         `json:"path"`
+	Kinds      []string       `json:"kinds"`
+	Verdicts   []watchVerdict `json:"verdicts"`
+}
+
+type server struct {
+	directories []string
+	args        arguments.Arguments
+	// listenHost is the host of the listen address, requests for other hosts are rejected
+	listenHost string
+	// scanning serializes scans, mutex guards the last scan which handlers read
+	scanning sync.Mutex
+	mutex    sync.RWMutex
+	last     scanResult
+}
+
+// Serve checks repositories and serves their verdicts as JSON together with a dashboard,
+// until it is interrupted
+func Serve(directories []string, args arguments.Arguments, serveArgs arguments.ServeArguments) error {
+	listenHost, _, err := net.SplitHostPort(serveArgs.Listen)
+	if err != nil {
+		return fmt.Errorf("invalid listen address %s\n%s", serveArgs.Listen, err)
+	}
+	s := &server{directories: directories, args: args, listenHost: listenHost}
+	err = s.scan()
+	if err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", s.handleDashboard)
+	mux.HandleFunc("GET /api/repositories", s.handleRepositories)
+	mux.HandleFunc("GET /api/verdicts", s.handleVerdicts)
+	mux.HandleFunc("GET /api/scan", s.handleLastScan)
+	mux.HandleFunc("POST /api/scan", s.handleScan)
+	mux.HandleFunc("GET /metrics", s.handleMetrics)
+	httpServer := &http.Server{
+		Addr:              serveArgs.Listen,
+		Handler:           s.guard(mux),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	go func() {
+		<-signals
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = httpServer.Shutdown(ctx)
+	}()
+	_, _ = fmt.Fprintf(os.Stderr, "serving on http://%s\n", serveArgs.Listen)
+	err = httpServer.ListenAndServe()
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("cannot serve on %s\n%s", serveArgs.Listen, err)
+	}
+	return nil
+}
+
+// scan checks every repository again, scans are serialized
+func (s *server) scan() error {
+	s.scanning.Lock()
+	defer s.scanning.Unlock()
+	result, err := scanDirectories(s.directories, s.args)
+	if err != nil {
+		return err
+	}
+	s.mutex.Lock()
+	s.last = result
+	s.mutex.Unlock()
+	return nil
+}
+
+func (s *server) verdictsOf(scan scanResult) []serverVerdict {
+	verdicts := make([]serverVerdict, 0, len(scan.verdicts))
+	for _, verdict := range scan.verdicts {
+		kind, details := describeVerdict(verdict, s.args.Files)
+		if kind == "" {
+			continue
+		}
+		fullPath := absolutePath(verdict)
+		verdicts = append(verdicts, serverVerdict{
+			Root:       scan.rootOf(fullPath),
+			Repository: verdict.RepositoryPath(),
+			Path:       fullPath,
+			Kind:       kind,
+			Details:    details,
+		})
+	}
+	return verdicts
+}
+
+func (s *server) repositoriesOf(scan scanResult) []serverRepository {
+	repositories := make([]serverRepository, 0)
+	// verdicts of a repository are not adjacent unless sorted by path
+	indexes := make(map[string]int)
+	for _, verdict := range s.verdictsOf(scan) {
+		index, ok := indexes[verdict.Path]
+		if !ok {
+			index = len(repositories)
+			indexes[verdict.Path] = index
+			repositories = append(repositories, serverRepository{
+				Root:       verdict.Root,
+				Repository: verdict.Repository,
+				Path:       verdict.Path,
+				Kinds:      []string{},
+				Verdicts:   []watchVerdict{},
+			})
+		}
+		repository := &repositories[index]
+		if !slices.Contains(repository.Kinds, verdict.Kind) {
+			repository.Kinds = append(repository.Kinds, verdict.Kind)
+		}
+		repository.Verdicts = append(repository.Verdicts, watchVerdict{Kind: verdict.Kind, Details: verdict.Details})
+	}
+	return repositories
+}
+
+// guard rejects requests for hosts other than the listen address and localhost, which DNS rebinding
+// would send from other sites, and cross-origin requests which are not reads
+func (s *server) guard(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.allowedHost(r.Host) {
+			writeJSONResponse(w, http.StatusForbidden, map[string]string{"error": "host is not allowed"})
+			return
+		}
+		if r.Method != http.MethodGet && r.Method != http.MethodHead && !sameOrigin(r) {
+			writeJSONResponse(w, http.StatusForbidden, map[string]string{"error": "cross-origin request is not allowed"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// allowedHost reports whether the Host header names the listen address, localhost or a loopback address.
+// Servers listening on every interface are reached by any IP address, which DNS rebinding cannot forge.
+func (s *server) allowedHost(host string) bool {
+	hostname, _, err := net.SplitHostPort(host)
+	if err != nil {
+		hostname = host
+	}
+	hostname = strings.Trim(hostname, "[]")
+	if strings.EqualFold(hostname, "localhost") || strings.EqualFold(hostname, s.listenHost) {
+		return true
+	}
+	ip := net.ParseIP(hostname)
+	if ip == nil {
+		return false
+	}
+	listenIP := net.ParseIP(s.listenHost)
+	return ip.IsLoopback() || s.listenHost == "" || (listenIP != nil && listenIP.IsUnspecified())
+}
+
+// sameOrigin reports whether the request was not sent by another site,
+// requests of tools such as curl have neither Origin nor Sec-Fetch-Site headers
+func sameOrigin(r *http.Request) bool {
+	site := r.Header.Get("Sec-Fetch-Site")
+	if site != "" && site != "same-origin" && site != "none" {
+		return false
+	}
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	originURL, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(originURL.Host, r.Host)
+}
+
+func (s *server) lastScan() scanResult {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.last
+}
+
+func (s *server) handleDashboard(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, _ = w.Write(dashboard)
+}
+
+func (s *server) handleRepositories(w http.ResponseWriter, _ *http.Request) {
+	writeJSONResponse(w, http.StatusOK, s.repositoriesOf(s.lastScan()))
+}
+
+func (s *server) handleVerdicts(w http.ResponseWriter, _ *http.Request) {
+	writeJSONResponse(w, http.StatusOK, s.verdictsOf(s.lastScan()))
+}
+
+func (s *server) handleLastScan(w http.ResponseWriter, _ *http.Request) {
+	writeJSONResponse(w, http.StatusOK, s.lastScan())
+}
+
+func (s *server) handleScan(w http.ResponseWriter, _ *http.Request) {
+	err := s.scan()
+	if err != nil {
+		writeJSONResponse(w, http.StatusInternalServerError, map[string]string{
+			"error": strings.ReplaceAll(err.Error(), "\n", ": "),
+		})
+		return
+	}
+	writeJSONResponse(w, http.StatusOK, s.lastScan())
+}
+
+func (s *server) handleMetrics(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", metricsContentType)
+	_ = writeMetrics(w, s.lastScan(), s.args)
+}
+
+func writeJSONResponse(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(value)
+}
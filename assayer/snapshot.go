@@ -0,0 +1,183 @@
+package assayer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/hov1417/assayer/arguments"
+	"github.com/hov1417/assayer/auth"
+	"github.com/hov1417/assayer/check"
+	"github.com/hov1417/assayer/types"
+)
+
+// snapshotRefs is the namespace of snapshot commits, one ref per snapshot time
+const snapshotRefs = "refs/assayer/wip/"
+
+// Snapshot records index and worktree, with untracked files, of every dirty repository
+// as a commit on refs/assayer/wip/<timestamp>, HEAD, the index and the worktree are left untouched
+func Snapshot(directories []string, args arguments.Arguments, snapshotArgs arguments.SnapshotArguments) error {
+	verdicts, err := collectVerdicts(directories, args)
+	if err != nil {
+		return fmt.Errorf("error checking repositories\n%s", err)
+	}
+
+	ref := snapshotRefs + time.Now().UTC().Format("20060102T150405.000Z")
+	provider := auth.NewProvider(args.SSHKeys)
+	defer provider.Finish()
+	detailed := len(directories) > 1
+	failed := 0
+	previous := ""
+	for _, verdict := range verdicts {
+		// verdicts are ordered by repository, each dirty repository is recorded once
+		if verdict.FullPath() == previous {
+			continue
+		}
+		previous = verdict.FullPath()
+
+		repoName := types.RepoName(verdict, detailed)
+		commit, err := snapshotRepository(verdict.FullPath(), ref)
+		if err == nil && snapshotArgs.Remote != "" {
+			err = pushSnapshot(verdict.FullPath(), snapshotArgs.Remote, ref, provider, args.FetchTimeout)
+		}
+		if err != nil {
+			failed += 1
+			err = reportRepoResult(
+				repoName,
+				"Snapshot Failed",
+				strings.ReplaceAll(err.Error(), "\n", ": "),
+				true,
+			)
+		} else if snapshotArgs.Remote != "" {
+			err = reportRepoResult(
+				repoName,
+				"Snapshot",
+				fmt.Sprintf("%s %s -> %s", commit[:7], ref, snapshotArgs.Remote),
+				true,
+			)
+		} else {
+			err = reportRepoResult(repoName, "Snapshot", fmt.Sprintf("%s %s", commit[:7], ref), true)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d repositories failed to snapshot", failed)
+	}
+	return nil
+}
+
+// snapshotRepository commits the state as `git stash --include-untracked` would,
+// without resetting anything, the worktree commit has HEAD and a commit of the index as parents
+func snapshotRepository(repositoryPath, ref string) (string, error) {
+	message := fmt.Sprintf("assayer snapshot %s", strings.TrimPrefix(ref, snapshotRefs))
+
+	// index and worktree are written from a copy of the index, the real index stays as it is
+	indexPath, err := check.RunGit(repositoryPath, "rev-parse", "--path-format=absolute", "--git-path", "index")
+	if err != nil {
+		return "", err
+	}
+	temporaryIndex, err := os.CreateTemp(filepath.Dir(strings.TrimSpace(string(indexPath))), "assayer-index-")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(temporaryIndex.Name())
+	index, err := os.ReadFile(strings.TrimSpace(string(indexPath)))
+	if err == nil {
+		_, err = temporaryIndex.Write(index)
+	}
+	closeErr := temporaryIndex.Close()
+	if err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+	if closeErr != nil {
+		return "", closeErr
+	}
+	env := []string{"GIT_INDEX_FILE=" + temporaryIndex.Name()}
+
+	var parents []string
+	head, err := check.RunGit(repositoryPath, "rev-parse", "--verify", "--quiet", "HEAD")
+	if err == nil {
+		parents = append(parents, "-p", strings.TrimSpace(string(head)))
+	} else {
+		// without commits an empty base commit stands for HEAD, so the snapshot can be applied as a stash
+		emptyIndex := temporaryIndex.Name() + ".empty"
+		defer os.Remove(emptyIndex)
+		emptyTree, err := check.RunGitWithEnv(repositoryPath, []string{"GIT_INDEX_FILE=" + emptyIndex}, "write-tree")
+		if err != nil {
+			return "", err
+		}
+		base, err := check.RunGit(
+			repositoryPath, "commit-tree", "-m", "base of "+message, strings.TrimSpace(string(emptyTree)),
+		)
+		if err != nil {
+			return "", err
+		}
+		parents = append(parents, "-p", strings.TrimSpace(string(base)))
+	}
+
+	// conflicted files cannot be written as a tree, they are recorded in the index as they are in the worktree
+	conflicted, err := check.RunGitWithEnv(repositoryPath, env, "diff", "--name-only", "--diff-filter=U", "-z")
+	if err != nil {
+		return "", err
+	}
+	if paths := strings.Split(strings.TrimRight(string(conflicted), "\x00"), "\x00"); paths[0] != "" {
+		_, err = check.RunGitWithEnv(repositoryPath, env, append([]string{"add", "--all", "--"}, paths...)...)
+		if err != nil {
+			return "", err
+		}
+	}
+	indexTree, err := check.RunGitWithEnv(repositoryPath, env, "write-tree")
+	if err != nil {
+		return "", err
+	}
+	indexCommit, err := check.RunGit(
+		repositoryPath,
+		append([]string{"commit-tree", "-m", "index of " + message}, append(parents, strings.TrimSpace(string(indexTree)))...)...,
+	)
+	if err != nil {
+		return "", err
+	}
+	parents = append(parents, "-p", strings.TrimSpace(string(indexCommit)))
+
+	_, err = check.RunGitWithEnv(repositoryPath, env, "add", "--all", ".")
+	if err != nil {
+		return "", err
+	}
+	worktreeTree, err := check.RunGitWithEnv(repositoryPath, env, "write-tree")
+	if err != nil {
+		return "", err
+	}
+
+	commit, err := check.RunGit(
+		repositoryPath,
+		append([]string{"commit-tree", "-m", message}, append(parents, strings.TrimSpace(string(worktreeTree)))...)...,
+	)
+	if err != nil {
+		return "", err
+	}
+	hash := strings.TrimSpace(string(commit))
+	// the zero old value makes update-ref fail instead of overwriting an existing snapshot
+	_, err = check.RunGit(repositoryPath, "update-ref", "-m", message, ref, hash, strings.Repeat("0", len(hash)))
+	if err != nil {
+		return "", err
+	}
+	return hash, nil
+}
+
+func pushSnapshot(
+	repositoryPath, remote, ref string,
+	provider *auth.Provider,
+	timeout time.Duration,
+) error {
+	repo, err := git.PlainOpen(repositoryPath)
+	if err != nil {
+		return fmt.Errorf("error opening git repository\n%s", err)
+	}
+	return pushRefSpec(repo, remote, config.RefSpec(ref+":"+ref), provider, timeout)
+}
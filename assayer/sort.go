@@ -0,0 +1,150 @@
+package assayer
+
+import (
+	"cmp"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hov1417/assayer/arguments"
+	"github.com/hov1417/assayer/check"
+	"github.com/hov1417/assayer/types"
+)
+
+// kindOrder is the order of checks, kinds are sorted in it
+var kindOrder = []string{
+	"Conflicted",
+	"Staged",
+	"Unstaged",
+	"Untracked",
+	"More Files",
+	"Stashed Changes",
+	"Local Only Branch",
+	"Remote Ahead",
+	"Remote Behind",
+	"Unpushed Tag",
+	"Unmodified",
+}
+
+type sortedVerdict struct {
+	verdict types.Verdict
+	path    string
+	kind    int
+	details string
+	age     time.Time
+}
+
+// sortVerdicts sorts verdicts in the order, ties are broken by repository path, kind and details
+func sortVerdicts(verdicts []types.Verdict, order arguments.SortOrder, files bool) {
+	sorted := make([]sortedVerdict, len(verdicts))
+	commitTimes := make(map[string]time.Time)
+	for i, verdict := range verdicts {
+		kind, details := describeVerdict(verdict, files)
+		sorted[i] = sortedVerdict{
+			verdict: verdict,
+			path:    verdict.FullPath(),
+			kind:    kindIndex(kind),
+			details: details,
+		}
+		if order == arguments.SortAge {
+			sorted[i].age = verdictTime(verdict, commitTimes)
+		}
+	}
+	slices.SortStableFunc(sorted, func(a, b sortedVerdict) int {
+		byPath := strings.Compare(a.path, b.path)
+		byKind := cmp.Compare(a.kind, b.kind)
+		byDetails := strings.Compare(a.details, b.details)
+		switch order {
+		case arguments.SortKind:
+			return cmp.Or(byKind, byPath, byDetails)
+		case arguments.SortAge:
+			return cmp.Or(compareAge(a.age, b.age), byPath, byKind, byDetails)
+		default:
+			return cmp.Or(byPath, byKind, byDetails)
+		}
+	})
+	for i := range sorted {
+		verdicts[i] = sorted[i].verdict
+	}
+}
+
+func kindIndex(kind string) int {
+	index := slices.Index(kindOrder, kind)
+	if index == -1 {
+		return len(kindOrder)
+	}
+	return index
+}
+
+// compareAge orders older times first, unknown times last
+func compareAge(a, b time.Time) int {
+	switch {
+	case a.IsZero() && b.IsZero():
+		return 0
+	case a.IsZero():
+		return 1
+	case b.IsZero():
+		return -1
+	}
+	return a.Compare(b)
+}
+
+// verdictTime returns when the work of the verdict was last changed, the modification time of files
+// and the commit time of stashes, branches and tags, otherwise the commit time of HEAD
+func verdictTime(verdict types.Verdict, commitTimes map[string]time.Time) time.Time {
+	repository := verdict.FullPath()
+	var file, revision string
+	switch verdict := verdict.(type) {
+	case check.Untracked:
+		file = verdict.UntrackedItem()
+	case check.Staged:
+		file = verdict.ModifiedItem()
+	case check.Unstaged:
+		file = verdict.ModifiedItem()
+	case check.Conflicted:
+		file = verdict.ModifiedItem()
+	case check.StashedChanges:
+		revision = "refs/stash"
+	case check.LocalOnlyBranch:
+		revision = "refs/heads/" + verdict.BranchName()
+	case check.RemoteBehind:
+		revision = "refs/heads/" + verdict.LocalBranch()
+	case check.RemoteAhead:
+		revision = "refs/remotes/" + verdict.RemoteRefName()
+	case check.UnpushedTag:
+		revision = "refs/tags/" + verdict.TagName()
+	}
+	if file != "" {
+		info, err := os.Lstat(filepath.Join(repository, file))
+		if err == nil {
+			return info.ModTime()
+		}
+	}
+	if revision != "" {
+		if commitTime := cachedCommitTime(repository, revision, commitTimes); !commitTime.IsZero() {
+			return commitTime
+		}
+	}
+	return cachedCommitTime(repository, "HEAD", commitTimes)
+}
+
+// cachedCommitTime returns the commit time of the revision, zero when it cannot be resolved
+func cachedCommitTime(repository, revision string, commitTimes map[string]time.Time) time.Time {
+	key := repository + "\x00" + revision
+	if commitTime, ok := commitTimes[key]; ok {
+		return commitTime
+	}
+	var commitTime time.Time
+	output, err := check.RunGit(repository, "log", "-1", "--format=%ct", revision, "--")
+	if err == nil {
+		seconds, err := strconv.ParseInt(strings.TrimSpace(string(output)), 10, 64)
+		if err == nil {
+			commitTime = time.Unix(seconds, 0)
+		}
+	}
+	commitTimes[key] = commitTime
+	return commitTime
+}
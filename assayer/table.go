@@ -0,0 +1,168 @@
+package assayer
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+
+	"github.com/hov1417/assayer/arguments"
+	"github.com/hov1417/assayer/types"
+)
+
+// minColumnWidth is the width columns are not shrunk below to fit the terminal
+const minColumnWidth = 12
+
+// kindColors are ANSI colors of verdict kinds, by their metric kinds
+var kindColors = map[string]string{
+	"conflicted":        "31",
+	"staged":            "32",
+	"unstaged":          "33",
+	"untracked":         "35",
+	"stashed_changes":   "36",
+	"local_only_branch": "34",
+	"remote_ahead":      "36",
+	"remote_behind":     "34",
+	"unpushed_tag":      "34",
+	"unmodified":        "2",
+	"more_files":        "2",
+}
+
+// colorful reports whether out is a terminal and colors are not disabled with NO_COLOR
+func colorful(out io.Writer) bool {
+	file, ok := out.(*os.File)
+	return ok && os.Getenv("NO_COLOR") == "" && term.IsTerminal(int(file.Fd()))
+}
+
+// terminalWidth returns the width of output, $COLUMNS or the width of the terminal, 0 when unknown
+func terminalWidth(out io.Writer) int {
+	if columns, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && columns > 0 {
+		return columns
+	}
+	if file, ok := out.(*os.File); ok {
+		if width, _, err := term.GetSize(int(file.Fd())); err == nil && width > 0 {
+			return width
+		}
+	}
+	return 0
+}
+
+// paint wraps text in the ANSI color when colors are enabled
+func paint(text, color string, enabled bool) string {
+	if !enabled || color == "" || text == "" {
+		return text
+	}
+	return "\x1b[" + color + "m" + text + "\x1b[0m"
+}
+
+// pad fills text with spaces up to width runes
+func pad(text string, width int) string {
+	return text + strings.Repeat(" ", max(width-utf8.RuneCountInString(text), 0))
+}
+
+// truncate cuts text to width runes, keeping its end when it is a path
+func truncate(text string, width int, keepEnd bool) string {
+	runes := []rune(text)
+	if len(runes) <= width || width <= 0 {
+		return text
+	}
+	if width == 1 {
+		return "…"
+	}
+	if keepEnd {
+		return "…" + string(runes[len(runes)-width+1:])
+	}
+	return string(runes[:width-1]) + "…"
+}
+
+// tableReporter prints verdicts as a table whose columns are sized to the terminal
+type tableReporter struct {
+	*scanReporter
+	out  io.Writer
+	args arguments.Arguments
+	// detailed names repositories with their checked directory, when several are checked
+	detailed bool
+}
+
+func newTableReporter(out io.Writer, args arguments.Arguments) Reporter {
+	return &tableReporter{scanReporter: newScanReporter(args), out: out, args: args}
+}
+
+func (r *tableReporter) Begin(directories []string) error {
+	r.detailed = len(directories) > 1
+	return r.scanReporter.Begin(directories)
+}
+
+// Error stops the table as the default format does
+func (r *tableReporter) Error(err error) error {
+	return err
+}
+
+func (r *tableReporter) End() error {
+	err := r.scanReporter.End()
+	if err != nil {
+		return err
+	}
+	// listing files is pointless without showing them
+	verbose := r.args.Verbose || r.args.Files
+	rows := [][3]string{{"REPOSITORY", "KIND", "DETAILS"}}
+	kinds := []string{""}
+	for _, verdict := range r.result.verdicts {
+		kind, details := describeVerdict(verdict, r.args.Files)
+		if kind == "" {
+			continue
+		}
+		if !verbose {
+			details = ""
+		}
+		rows = append(rows, [3]string{types.RepoName(verdict, r.detailed), kind, details})
+		kinds = append(kinds, metricKind(kind))
+	}
+	if len(rows) == 1 {
+		return nil
+	}
+
+	widths := [3]int{}
+	for _, row := range rows {
+		for column, cell := range row {
+			widths[column] = max(widths[column], utf8.RuneCountInString(cell))
+		}
+	}
+	if !verbose {
+		widths[2] = 0
+	}
+	// details and then repositories are shrunk until the table fits
+	if width := terminalWidth(r.out); width > 0 {
+		overflow := widths[0] + widths[1] + widths[2] + 4 - width
+		for _, column := range []int{2, 0} {
+			if overflow <= 0 {
+				break
+			}
+			shrink := min(overflow, max(widths[column]-minColumnWidth, 0))
+			widths[column] -= shrink
+			overflow -= shrink
+		}
+	}
+
+	color := colorful(r.out)
+	for i, row := range rows {
+		repository := pad(truncate(row[0], widths[0], true), widths[0])
+		kind := truncate(row[1], widths[1], false)
+		line := repository + "  " + paint(kind, kindColors[kinds[i]], color)
+		if widths[2] > 0 && row[2] != "" {
+			line += pad("", widths[1]-utf8.RuneCountInString(kind)) + "  " + truncate(row[2], widths[2], false)
+		}
+		if i == 0 {
+			line = paint(line, "1", color)
+		}
+		_, err = fmt.Fprintln(r.out, line)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
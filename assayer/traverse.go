@@ -9,63 +9,122 @@ import (
 	"sync"
 
 	"github.com/hov1417/assayer/arguments"
+	"github.com/hov1417/assayer/auth"
+	"github.com/hov1417/assayer/cache"
 	"github.com/hov1417/assayer/check"
 	"github.com/hov1417/assayer/types"
 )
 
 type Directory struct {
-	readDir []fs.DirEntry
-	path    string
+	directories []string
+	path        string
 }
 
+// TraverseDirectories checks repositories of directories and reports them in the format of arguments
 func TraverseDirectories(directories []string, args arguments.Arguments) error {
+	args.SkipIgnored = true
+	reporter, err := NewReporter(args.Format, os.Stdout, args)
+	if err != nil {
+		return err
+	}
+	return Report(directories, args, reporter)
+}
 
-	var repositories = make(chan RepositoryRecord, 100)
-	wg := sync.WaitGroup{}
-
-	for _, dir := range directories {
-		err := findRepositories(dir, repositories, &wg, args.Nested)
-		if err != nil {
-			return fmt.Errorf("error finding repositories\n%s", err)
-		}
+// CheckDirectories checks repositories found in directories and passes their verdicts to handle
+func CheckDirectories(
+	directories []string,
+	args arguments.Arguments,
+	handle func(verdicts chan types.Response) error,
+) error {
+	index, err := cache.LoadIndex()
+	if err != nil {
+		return fmt.Errorf("error loading repository index\n%s", err)
 	}
-	go func() {
-		wg.Wait()
-		close(repositories)
-	}()
 
-	fetcherChecker := check.FetcherChecker{
-		FetchType:  args.FetchType,
-		FetchGroup: args.FetchGroup,
+	repositories, err := findAllRepositories(index, directories, args)
+	if err != nil {
+		return err
 	}
+	fetcherChecker := newFetcherChecker(args)
 
 	verdicts, err := checkRepositories(repositories, args, fetcherChecker)
 	if err != nil {
 		return fmt.Errorf("error checking repositories\n%s", err)
 	}
 
-	if args.Count {
-		err = ReportResultByCount(verdicts, args)
-	} else if args.Reporter != nil {
-		err = ReportResultWithReporter(verdicts, args)
-	} else {
-		err = ReportResults(verdicts, args, len(directories) > 1)
-	}
-
+	defer fetcherChecker.Scheduler.Finish()
+	defer fetcherChecker.Auth.Finish()
+	err = handle(verdicts)
 	if err != nil {
 		return err
 	}
 
+	err = index.Save()
+	if err != nil {
+		return fmt.Errorf("error saving repository index\n%s", err)
+	}
 	return nil
 }
 
+// findAllRepositories finds repositories of directories, the channel is closed once all are found
+func findAllRepositories(
+	index *cache.Index,
+	directories []string,
+	args arguments.Arguments,
+) (chan RepositoryRecord, error) {
+	var repositories = make(chan RepositoryRecord, 100)
+	wg := sync.WaitGroup{}
+
+	scans := make([]*cache.RootScan, 0, len(directories))
+	for _, dir := range directories {
+		scan, err := index.Scan(dir, args.Rescan)
+		if err != nil {
+			return nil, fmt.Errorf("error finding repositories\n%s", err)
+		}
+		scans = append(scans, scan)
+		err = findRepositories(dir, scan, repositories, &wg, args.Nested)
+		if err != nil {
+			return nil, fmt.Errorf("error finding repositories\n%s", err)
+		}
+	}
+	go func() {
+		wg.Wait()
+		for _, scan := range scans {
+			scan.Finish()
+		}
+		close(repositories)
+	}()
+	return repositories, nil
+}
+
+func newFetcherChecker(args arguments.Arguments) check.FetcherChecker {
+	return check.FetcherChecker{
+		FetchType:   args.FetchType,
+		FetchGroup:  args.FetchGroup,
+		FetchHost:   args.FetchHost,
+		FetchPath:   args.FetchPath,
+		FetchRemote: args.FetchRemote,
+		Probe:       args.ProbeRemote,
+		Prune:       args.FetchPrune,
+		Tags:        args.FetchTags,
+		Auth:        auth.NewProvider(args.SSHKeys),
+		Scheduler: check.NewFetchScheduler(
+			args.FetchJobs,
+			args.FetchHostJobs,
+			args.FetchRetries,
+			args.FetchTimeout,
+		),
+		MaxAge: args.FetchMaxAge,
+	}
+}
+
 func checkRepositories(
 	repositories chan RepositoryRecord,
 	args arguments.Arguments,
 	checker check.FetcherChecker,
 ) (chan types.Response, error) {
 	verdicts := make(chan types.Response, 100)
-	assayer := check.NewAssayer(args)
+	assayer := check.NewAssayer(args, &checker)
 
 	var wg sync.WaitGroup
 	for repositoryRecord := range repositories {
@@ -93,22 +152,23 @@ type RepositoryRecord struct {
 
 func findRepositories(
 	directory string,
+	scan *cache.RootScan,
 	repositories chan RepositoryRecord,
 	wg *sync.WaitGroup,
 	nestedRepos bool,
 ) error {
 	dirFs := os.DirFS(directory)
 
-	readDir, err := fs.ReadDir(dirFs, ".")
+	directories, err := scan.Directories(dirFs, ".")
 	if err != nil {
 		return err
 	}
 	entry := Directory{
-		readDir,
+		directories,
 		".",
 	}
 	wg.Add(1)
-	go handleDirEntry(dirFs, directory, entry, wg, repositories, nestedRepos)
+	go handleDirEntry(dirFs, directory, scan, entry, wg, repositories, nestedRepos)
 
 	return nil
 }
@@ -116,6 +176,7 @@ func findRepositories(
 func handleDirEntry(
 	dirFs fs.FS,
 	rootDirectory string,
+	scan *cache.RootScan,
 	directory Directory,
 	wg *sync.WaitGroup,
 	repositories chan RepositoryRecord,
@@ -123,35 +184,34 @@ func handleDirEntry(
 ) {
 	stop := false
 	if !nestedRepos {
-		for _, entry := range directory.readDir {
-			if entry.IsDir() && entry.Name() == ".git" {
+		for _, name := range directory.directories {
+			if name == ".git" {
 				stop = true
 			}
 		}
 	}
 
-	for _, entry := range directory.readDir {
-		if entry.IsDir() {
-			path := filepath.Join(directory.path, entry.Name())
+	for _, name := range directory.directories {
+		path := filepath.Join(directory.path, name)
+
+		if strings.HasSuffix(path, ".git") {
+			repository := filepath.Dir(path)
+			scan.AddRepository(repository)
+			repositories <- RepositoryRecord{&repository, &rootDirectory, nil}
+		}
 
-			if strings.HasSuffix(path, ".git") {
-				repository := filepath.Dir(path)
-				repositories <- RepositoryRecord{&repository, &rootDirectory, nil}
+		if !stop {
+			directories, err := scan.Directories(dirFs, path)
+			if err != nil {
+				repositories <- RepositoryRecord{nil, nil, err}
 			}
 
-			if !stop {
-				readDir, err := fs.ReadDir(dirFs, path)
-				if err != nil {
-					repositories <- RepositoryRecord{nil, nil, err}
-				}
-
-				dirEntry := Directory{
-					readDir: readDir,
-					path:    path,
-				}
-				wg.Add(1)
-				go handleDirEntry(dirFs, rootDirectory, dirEntry, wg, repositories, nestedRepos)
+			dirEntry := Directory{
+				directories: directories,
+				path:        path,
 			}
+			wg.Add(1)
+			go handleDirEntry(dirFs, rootDirectory, scan, dirEntry, wg, repositories, nestedRepos)
 		}
 	}
 	wg.Done()
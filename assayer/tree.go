@@ -0,0 +1,151 @@
+package assayer
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/hov1417/assayer/arguments"
+	"github.com/hov1417/assayer/check"
+	"github.com/hov1417/assayer/types"
+)
+
+// treeFiles is the number of files shown per repository in the tree
+const treeFiles = 3
+
+// treeRepository is a repository of the tree with its verdicts
+type treeRepository struct {
+	path     string
+	name     string
+	verdicts []types.Verdict
+}
+
+// treeReporter prints verdicts grouped under checked directories and repositories,
+// with the branch of every repository and its commits ahead and behind of the upstream
+type treeReporter struct {
+	*scanReporter
+	out io.Writer
+}
+
+func newTreeReporter(out io.Writer, args arguments.Arguments) Reporter {
+	return &treeReporter{scanReporter: newScanReporter(args), out: out}
+}
+
+// Error stops the tree as the default format does
+func (r *treeReporter) Error(err error) error {
+	return err
+}
+
+func (r *treeReporter) End() error {
+	err := r.scanReporter.End()
+	if err != nil {
+		return err
+	}
+	roots := make(map[string][]*treeRepository, len(r.result.roots))
+	// verdicts of a repository are not adjacent unless sorted by path
+	repositories := make(map[string]*treeRepository)
+	for _, verdict := range r.result.verdicts {
+		fullPath := absolutePath(verdict)
+		repository, ok := repositories[fullPath]
+		if !ok {
+			repository = &treeRepository{path: fullPath, name: verdict.Repository()}
+			repositories[fullPath] = repository
+			root := r.result.rootOf(fullPath)
+			roots[root] = append(roots[root], repository)
+		}
+		repository.verdicts = append(repository.verdicts, verdict)
+	}
+
+	color := colorful(r.out)
+	var tree strings.Builder
+	for _, root := range r.result.roots {
+		repositories := roots[root]
+		if len(repositories) == 0 {
+			continue
+		}
+		tree.WriteString(paint(root, "1", color))
+		tree.WriteString("\n")
+		for i, repository := range repositories {
+			last := i == len(repositories)-1
+			r.writeRepository(&tree, repository, last, color)
+		}
+	}
+	_, err = io.WriteString(r.out, tree.String())
+	return err
+}
+
+func (r *treeReporter) writeRepository(tree *strings.Builder, repository *treeRepository, last bool, color bool) {
+	branch, indent := "├── ", "│   "
+	if last {
+		branch, indent = "└── ", "    "
+	}
+	tree.WriteString(branch + paint(repository.name, "1", color))
+	if head := branchStatus(repository.path); head != "" {
+		tree.WriteString("  " + paint(head, "2", color))
+	}
+	tree.WriteString("\n")
+
+	type line struct{ kind, details string }
+	var lines []line
+	files, moreFiles := 0, 0
+	for _, verdict := range repository.verdicts {
+		switch verdict := verdict.(type) {
+		case check.MoreFiles:
+			moreFiles += verdict.Count()
+			continue
+		case check.Staged, check.Unstaged, check.Conflicted, check.Untracked:
+			files += 1
+			if files > treeFiles {
+				moreFiles += 1
+				continue
+			}
+		}
+		kind, details := describeVerdict(verdict, true)
+		if kind == "" {
+			continue
+		}
+		lines = append(lines, line{kind, details})
+	}
+	if moreFiles > 0 {
+		lines = append(lines, line{"More Files", fmt.Sprintf("%d more files are not shown", moreFiles)})
+	}
+
+	kindWidth := 0
+	for _, l := range lines {
+		kindWidth = max(kindWidth, utf8.RuneCountInString(l.kind))
+	}
+	for i, l := range lines {
+		leaf := "├── "
+		if i == len(lines)-1 {
+			leaf = "└── "
+		}
+		tree.WriteString(indent + leaf + paint(l.kind, kindColors[metricKind(l.kind)], color))
+		if l.details != "" {
+			tree.WriteString(pad("", kindWidth-utf8.RuneCountInString(l.kind)) + "  " + l.details)
+		}
+		tree.WriteString("\n")
+	}
+}
+
+// branchStatus describes the checked out branch with commits ahead (↑) and behind (↓) of its upstream
+func branchStatus(path string) string {
+	output, err := check.RunGit(path, "rev-parse", "--abbrev-ref", "HEAD")
+	if err != nil {
+		return ""
+	}
+	branch := strings.TrimSpace(string(output))
+	if branch == "HEAD" {
+		return "(detached)"
+	}
+	output, err = check.RunGit(path, "rev-list", "--left-right", "--count", "HEAD...@{upstream}")
+	if err != nil {
+		return branch
+	}
+	var ahead, behind int
+	_, err = fmt.Sscan(string(output), &ahead, &behind)
+	if err != nil {
+		return branch
+	}
+	return fmt.Sprintf("%s ↑%d ↓%d", branch, ahead, behind)
+}
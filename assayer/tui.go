@@ -0,0 +1,456 @@
+package assayer
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+
+	"github.com/hov1417/assayer/arguments"
+	"github.com/hov1417/assayer/auth"
+	"github.com/hov1417/assayer/check"
+	"github.com/hov1417/assayer/types"
+	"golang.org/x/term"
+)
+
+const tuiHelp = "↑/↓ move  enter expand  p push  f pull  d drop stash  D delete merged branch  s shell  i ignore  r refresh  q quit"
+
+// tuiRepository is a repository with its verdicts, stashes are listed once it is expanded
+type tuiRepository struct {
+	path     string
+	name     string
+	verdicts []types.Verdict
+	stashes  []string
+	expanded bool
+}
+
+// tuiRow is a line of the view, a repository or an item of an expanded repository
+type tuiRow struct {
+	repository *tuiRepository
+	verdict    types.Verdict
+	// stash is the name of a stash entry, e.g. stash@{0}
+	stash string
+	text  string
+}
+
+type tui struct {
+	directories  []string
+	args         arguments.Arguments
+	tuiArgs      arguments.TuiArguments
+	backend      check.Backend
+	provider     *auth.Provider
+	repositories []*tuiRepository
+	rows         []tuiRow
+	cursor       int
+	offset       int
+	message      string
+	state        *term.State
+}
+
+// Tui lists repositories with findings in a navigable terminal view and runs actions on the selected item
+func Tui(directories []string, args arguments.Arguments, tuiArgs arguments.TuiArguments) error {
+	if !term.IsTerminal(int(os.Stdin.Fd())) || !term.IsTerminal(int(os.Stdout.Fd())) {
+		return fmt.Errorf("tui requires a terminal")
+	}
+	args.SkipIgnored = true
+	t := &tui{
+		directories: directories,
+		args:        args,
+		tuiArgs:     tuiArgs,
+		backend:     check.NewBackend(args.Backend),
+		provider:    auth.NewProvider(args.SSHKeys),
+	}
+	defer t.provider.Finish()
+	err := t.load()
+	if err != nil {
+		return err
+	}
+	// later refreshes show results of actions, remotes are not fetched again
+	t.args.FetchType = arguments.FetchNone
+	t.args.ProbeRemote = false
+
+	t.state, err = term.MakeRaw(int(os.Stdin.Fd()))
+	if err != nil {
+		return fmt.Errorf("cannot set terminal to raw mode\n%s", err)
+	}
+	defer func() {
+		_ = term.Restore(int(os.Stdin.Fd()), t.state)
+		fmt.Print("\x1b[?25h\x1b[H\x1b[2J")
+	}()
+	fmt.Print("\x1b[?25l")
+	for {
+		t.render()
+		key, err := readKey()
+		if err != nil {
+			return err
+		}
+		if key == "q" || key == "\x03" {
+			return nil
+		}
+		t.message = ""
+		err = t.handle(key)
+		if err != nil {
+			t.message = strings.ReplaceAll(err.Error(), "\n", ": ")
+		}
+	}
+}
+
+// load checks directories and keeps expanded repositories expanded
+func (t *tui) load() error {
+	verdicts, err := collectVerdicts(t.directories, t.args)
+	if err != nil {
+		return fmt.Errorf("error checking repositories\n%s", err)
+	}
+	expanded := make(map[string]bool)
+	for _, repository := range t.repositories {
+		expanded[repository.path] = repository.expanded
+	}
+
+	detailed := len(t.directories) > 1
+	t.repositories = nil
+	for _, verdict := range verdicts {
+		if len(t.repositories) == 0 || t.repositories[len(t.repositories)-1].path != verdict.FullPath() {
+			t.repositories = append(t.repositories, &tuiRepository{
+				path: verdict.FullPath(),
+				name: types.RepoName(verdict, detailed),
+			})
+		}
+		repository := t.repositories[len(t.repositories)-1]
+		repository.verdicts = append(repository.verdicts, verdict)
+	}
+	for _, repository := range t.repositories {
+		if expanded[repository.path] {
+			err = repository.expand()
+			if err != nil {
+				return err
+			}
+		}
+	}
+	t.buildRows()
+	return nil
+}
+
+func (r *tuiRepository) expand() error {
+	r.expanded = true
+	r.stashes = nil
+	output, err := check.RunGit(r.path, "stash", "list", "--format=%gd %s")
+	if err != nil {
+		return err
+	}
+	for line := range strings.Lines(string(output)) {
+		r.stashes = append(r.stashes, strings.TrimSuffix(line, "\n"))
+	}
+	return nil
+}
+
+// summary lists kinds of verdicts of the repository in the order they were found
+func (r *tuiRepository) summary() string {
+	var kinds []string
+	for _, verdict := range r.verdicts {
+		verdictType, _ := describeVerdict(verdict, true)
+		if verdictType != "" && !slices.Contains(kinds, verdictType) {
+			kinds = append(kinds, verdictType)
+		}
+	}
+	return strings.Join(kinds, ", ")
+}
+
+func (t *tui) buildRows() {
+	t.rows = nil
+	for _, repository := range t.repositories {
+		marker := "▸"
+		if repository.expanded {
+			marker = "▾"
+		}
+		t.rows = append(t.rows, tuiRow{
+			repository: repository,
+			text:       fmt.Sprintf("%s %-58s %s", marker, repository.name, repository.summary()),
+		})
+		if !repository.expanded {
+			continue
+		}
+		for _, verdict := range repository.verdicts {
+			// stashes are listed one by one below
+			if _, ok := verdict.(check.StashedChanges); ok {
+				continue
+			}
+			verdictType, details := describeVerdict(verdict, true)
+			if verdictType == "" {
+				continue
+			}
+			t.rows = append(t.rows, tuiRow{
+				repository: repository,
+				verdict:    verdict,
+				text:       fmt.Sprintf("    %-40s %s", verdictType, details),
+			})
+		}
+		for _, stash := range repository.stashes {
+			name, description, _ := strings.Cut(stash, " ")
+			t.rows = append(t.rows, tuiRow{
+				repository: repository,
+				stash:      name,
+				text:       fmt.Sprintf("    %-40s %s %s", "Stash", name, description),
+			})
+		}
+	}
+	t.cursor = max(min(t.cursor, len(t.rows)-1), 0)
+}
+
+func (t *tui) render() {
+	width, height, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil || width <= 0 || height <= 0 {
+		width, height = 80, 24
+	}
+	// header, help and message lines
+	visible := max(height-3, 1)
+	if t.cursor < t.offset {
+		t.offset = t.cursor
+	} else if t.cursor >= t.offset+visible {
+		t.offset = t.cursor - visible + 1
+	}
+
+	var screen strings.Builder
+	screen.WriteString("\x1b[H\x1b[2J")
+	screen.WriteString(fit(fmt.Sprintf("assayer: %d repositories with findings", len(t.repositories)), width))
+	screen.WriteString("\r\n")
+	if len(t.rows) == 0 {
+		screen.WriteString("nothing to triage\r\n")
+	}
+	for i := t.offset; i < len(t.rows) && i < t.offset+visible; i++ {
+		line := fit(t.rows[i].text, width)
+		if i == t.cursor {
+			line = "\x1b[7m" + line + "\x1b[0m"
+		}
+		screen.WriteString(line)
+		screen.WriteString("\r\n")
+	}
+	screen.WriteString(fmt.Sprintf("\x1b[%d;1H", height-1))
+	screen.WriteString(fit(t.message, width))
+	screen.WriteString(fmt.Sprintf("\x1b[%d;1H\x1b[2m", height))
+	screen.WriteString(fit(tuiHelp, width))
+	screen.WriteString("\x1b[0m")
+	fmt.Print(screen.String())
+}
+
+// fit cuts the line to the terminal width
+func fit(line string, width int) string {
+	runes := []rune(line)
+	if len(runes) > width {
+		return string(runes[:width])
+	}
+	return line
+}
+
+// readKey reads a key press, arrow keys are returned as their escape sequence
+func readKey() (string, error) {
+	key, err := stdin.ReadByte()
+	if err != nil {
+		return "", fmt.Errorf("cannot read key\n%s", err)
+	}
+	if key != '\x1b' || stdin.Buffered() < 2 {
+		return string(key), nil
+	}
+	sequence := make([]byte, 2)
+	_, err = stdin.Read(sequence)
+	if err != nil {
+		return "", fmt.Errorf("cannot read key\n%s", err)
+	}
+	return "\x1b" + string(sequence), nil
+}
+
+func (t *tui) handle(key string) error {
+	if len(t.rows) == 0 {
+		if key == "r" {
+			return t.load()
+		}
+		return nil
+	}
+	row := t.rows[t.cursor]
+	switch key {
+	case "j", "\x1b[B":
+		t.cursor = min(t.cursor+1, len(t.rows)-1)
+	case "k", "\x1b[A":
+		t.cursor = max(t.cursor-1, 0)
+	case "\r", " ", "l", "\x1b[C":
+		if row.repository.expanded {
+			if key == "\r" || key == " " {
+				row.repository.expanded = false
+			}
+		} else {
+			err := row.repository.expand()
+			if err != nil {
+				return err
+			}
+		}
+		t.buildRows()
+	case "h", "\x1b[D":
+		row.repository.expanded = false
+		t.buildRows()
+		t.cursor = slices.IndexFunc(t.rows, func(r tuiRow) bool { return r.repository == row.repository })
+	case "r":
+		return t.load()
+	case "p":
+		return t.push(row)
+	case "f":
+		return t.pull(row)
+	case "d":
+		return t.dropStash(row)
+	case "D":
+		return t.deleteBranch(row)
+	case "s":
+		return t.shell(row)
+	case "i":
+		return t.ignore(row)
+	}
+	return nil
+}
+
+func (t *tui) push(row tuiRow) error {
+	var push branchPush
+	switch verdict := row.verdict.(type) {
+	case check.RemoteBehind:
+		push = branchPush{branch: verdict.LocalBranch(), remote: verdict.RemoteName(), remoteBranch: verdict.RemoteBranch()}
+	case check.LocalOnlyBranch:
+		push = branchPush{
+			branch:       verdict.BranchName(),
+			remote:       t.tuiArgs.Remote,
+			remoteBranch: verdict.BranchName(),
+			setUpstream:  true,
+		}
+	default:
+		t.message = "select a branch which is behind its remote or local only to push"
+		return nil
+	}
+	err := pushBranch(row.repository.path, push, t.provider, t.args.FetchTimeout)
+	if err != nil {
+		return err
+	}
+	return t.done(fmt.Sprintf("pushed %s", push))
+}
+
+func (t *tui) pull(row tuiRow) error {
+	remoteAhead, ok := row.verdict.(check.RemoteAhead)
+	if !ok {
+		t.message = "select a branch whose remote is ahead to pull"
+		return nil
+	}
+	skipReason, err := pullBranch(remoteAhead, t.backend, false)
+	if err != nil {
+		return err
+	}
+	if skipReason != "" {
+		t.message = fmt.Sprintf("cannot fast-forward %s: %s", remoteAhead.LocalBranch(), skipReason)
+		return nil
+	}
+	return t.done(fmt.Sprintf("pulled %s <- %s", remoteAhead.LocalBranch(), remoteAhead.RemoteRefName()))
+}
+
+func (t *tui) dropStash(row tuiRow) error {
+	if row.stash == "" {
+		t.message = "select a stash to drop"
+		return nil
+	}
+	confirmed, err := t.confirm(fmt.Sprintf("Drop %s of %s?", row.stash, row.repository.name))
+	if err != nil || !confirmed {
+		return err
+	}
+	_, err = check.RunGit(row.repository.path, "stash", "drop", "--quiet", row.stash)
+	if err != nil {
+		return err
+	}
+	return t.done(fmt.Sprintf("dropped %s", row.stash))
+}
+
+// deleteBranch deletes the branch with `git branch -d`, which refuses branches that are not merged
+func (t *tui) deleteBranch(row tuiRow) error {
+	var branch string
+	switch verdict := row.verdict.(type) {
+	case check.LocalOnlyBranch:
+		branch = verdict.BranchName()
+	case check.RemoteBehind:
+		branch = verdict.LocalBranch()
+	case check.RemoteAhead:
+		branch = verdict.LocalBranch()
+	default:
+		t.message = "select a branch to delete"
+		return nil
+	}
+	confirmed, err := t.confirm(fmt.Sprintf("Delete merged branch %s of %s?", branch, row.repository.name))
+	if err != nil || !confirmed {
+		return err
+	}
+	_, err = check.RunGit(row.repository.path, "branch", "--delete", branch)
+	if err != nil {
+		return err
+	}
+	return t.done(fmt.Sprintf("deleted branch %s", branch))
+}
+
+// shell opens $SHELL in the repository, the view is refreshed when it exits
+func (t *tui) shell(row tuiRow) error {
+	shell := os.Getenv("SHELL")
+	if shell == "" {
+		shell = "/bin/sh"
+	}
+	err := term.Restore(int(os.Stdin.Fd()), t.state)
+	if err != nil {
+		return err
+	}
+	fmt.Print("\x1b[?25h\x1b[H\x1b[2J")
+	command := exec.Command(shell)
+	command.Dir = row.repository.path
+	command.Stdin = os.Stdin
+	command.Stdout = os.Stdout
+	command.Stderr = os.Stderr
+	shellErr := command.Run()
+
+	t.state, err = term.MakeRaw(int(os.Stdin.Fd()))
+	if err != nil {
+		return fmt.Errorf("cannot set terminal to raw mode\n%s", err)
+	}
+	fmt.Print("\x1b[?25l")
+	err = t.load()
+	if err != nil {
+		return err
+	}
+	if shellErr != nil {
+		t.message = fmt.Sprintf("shell exited: %s", shellErr)
+	}
+	return nil
+}
+
+// ignore sets assayer.ignore in the repository configuration, so later checks skip it
+func (t *tui) ignore(row tuiRow) error {
+	confirmed, err := t.confirm(fmt.Sprintf("Ignore %s in later checks?", row.repository.name))
+	if err != nil || !confirmed {
+		return err
+	}
+	_, err = check.RunGit(row.repository.path, "config", "assayer.ignore", "true")
+	if err != nil {
+		return err
+	}
+	return t.done(fmt.Sprintf("ignored %s, undo with `git config --unset assayer.ignore`", row.repository.name))
+}
+
+// confirm asks the question on the message line, no is the default
+func (t *tui) confirm(question string) (bool, error) {
+	t.message = question + " [y/N]"
+	t.render()
+	key, err := readKey()
+	t.message = ""
+	if err != nil {
+		return false, err
+	}
+	return key == "y" || key == "Y", nil
+}
+
+// done refreshes the view after an action and shows its result
+func (t *tui) done(message string) error {
+	err := t.load()
+	if err != nil {
+		return err
+	}
+	t.message = message
+	return nil
+}
@@ -0,0 +1,484 @@
+package assayer
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/hov1417/assayer/arguments"
+	"github.com/hov1417/assayer/cache"
+	"github.com/hov1417/assayer/check"
+	"github.com/hov1417/assayer/types"
+	"golang.org/x/term"
+)
+
+type watchedRepository struct {
+	directory  string
+	repository string
+	path       string
+	gitDir     string
+	// polled repositories could not be watched, fingerprint is their state at the last poll
+	polled        bool
+	fingerprint   string
+	fingerprinter cache.Fingerprinter
+}
+
+type watchVerdict struct {
+	Kind    string `json:"kind"`
+	Details string `json:"details,omitempty"`
+}
+
+// watchRecord is the result of checking a repository, printed as a line of JSON
+type watchRecord struct {
+	// Time is when the check started, results of checks started earlier are older
+	Time       time.Time      `json:"time"`
+	Repository string         `json:"repository"`
+	Path       string         `json:"path"`
+	Verdicts   []watchVerdict `json:"verdicts"`
+	Error      string         `json:"error,omitempty"`
+	verdicts   []types.Verdict
+}
+
+// kinds lists distinct kinds of verdicts of the record
+func (r watchRecord) kinds() []string {
+	var kinds []string
+	for _, verdict := range r.Verdicts {
+		if !slices.Contains(kinds, verdict.Kind) {
+			kinds = append(kinds, verdict.Kind)
+		}
+	}
+	return kinds
+}
+
+type watchState struct {
+	args         arguments.Arguments
+	watchArgs    arguments.WatchArguments
+	detailed     bool
+	terminal     bool
+	repositories map[string]*watchedRepository
+	// mutex guards results, which are read by queries of the daemon
+	mutex   sync.RWMutex
+	results map[string]watchRecord
+	// owners maps watched directories to path of their repository
+	owners  map[string]string
+	watcher *fsnotify.Watcher
+}
+
+// Watch checks repositories and keeps checking those which change, until it is interrupted
+func Watch(directories []string, args arguments.Arguments, watchArgs arguments.WatchArguments) error {
+	w, err := newWatchState(directories, args, watchArgs)
+	if err != nil {
+		return err
+	}
+	defer w.close()
+
+	// remotes are fetched once, later checks are caused by local changes
+	fetcher := newFetcherChecker(args)
+	assayer := check.NewAssayer(args, &fetcher)
+	w.report(w.update(w.check(slices.Collect(maps.Keys(w.repositories)), &assayer, &fetcher)))
+	fetcher.Scheduler.Finish()
+	fetcher.Auth.Finish()
+	return w.loop(func(changed []watchRecord) {
+		if len(changed) > 0 {
+			w.report(changed)
+		}
+	}, nil)
+}
+
+// newWatchState finds repositories of directories and starts watching them
+func newWatchState(
+	directories []string,
+	args arguments.Arguments,
+	watchArgs arguments.WatchArguments,
+) (*watchState, error) {
+	index, err := cache.LoadIndex()
+	if err != nil {
+		return nil, fmt.Errorf("error loading repository index\n%s", err)
+	}
+	found, err := findAllRepositories(index, directories, args)
+	if err != nil {
+		return nil, err
+	}
+	w := &watchState{
+		args:         args,
+		watchArgs:    watchArgs,
+		detailed:     len(directories) > 1,
+		terminal:     !watchArgs.JSON && term.IsTerminal(int(os.Stdout.Fd())),
+		repositories: make(map[string]*watchedRepository),
+		results:      make(map[string]watchRecord),
+		owners:       make(map[string]string),
+	}
+	for record := range found {
+		if record.err != nil {
+			return nil, fmt.Errorf("error finding repositories\n%s", record.err)
+		}
+		fullPath := filepath.Join(*record.rootDirectory, *record.repository)
+		gitDir, err := cache.GitDir(fullPath)
+		if err != nil {
+			return nil, fmt.Errorf("error finding git directory of %s\n%s", fullPath, err)
+		}
+		w.repositories[fullPath] = &watchedRepository{
+			directory:  *record.rootDirectory,
+			repository: *record.repository,
+			path:       fullPath,
+			gitDir:     gitDir,
+		}
+	}
+	err = index.Save()
+	if err != nil {
+		return nil, fmt.Errorf("error saving repository index\n%s", err)
+	}
+
+	if !watchArgs.Poll {
+		w.watcher, err = fsnotify.NewWatcher()
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "cannot watch repositories, falling back to polling\n%s\n", err)
+		}
+	}
+	for _, repository := range w.repositories {
+		if w.watcher != nil {
+			err = w.watch(repository)
+		}
+		if w.watcher == nil || err != nil {
+			w.poll(repository)
+		}
+	}
+	return w, nil
+}
+
+func (w *watchState) close() {
+	if w.watcher != nil {
+		_ = w.watcher.Close()
+	}
+}
+
+// loop checks changed repositories without fetching and passes records whose verdicts changed to handle,
+// records received from refreshes are handled too, it returns once the process is interrupted
+func (w *watchState) loop(handle func(changed []watchRecord), refreshes <-chan []watchRecord) error {
+	localArgs := w.args
+	localArgs.FetchType = arguments.FetchNone
+	localArgs.ProbeRemote = false
+	localFetcher := check.FetcherChecker{}
+	localAssayer := check.NewAssayer(localArgs, &localFetcher)
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	debounce := time.NewTimer(time.Hour)
+	debounce.Stop()
+	poll := time.NewTicker(w.watchArgs.PollInterval)
+	defer poll.Stop()
+	var events chan fsnotify.Event
+	var watchErrors chan error
+	if w.watcher != nil {
+		events = w.watcher.Events
+		watchErrors = w.watcher.Errors
+	}
+
+	pending := make(map[string]bool)
+	for {
+		select {
+		case <-signals:
+			return nil
+		case event := <-events:
+			repositoryPath := w.handleEvent(event)
+			if repositoryPath != "" {
+				pending[repositoryPath] = true
+				debounce.Reset(w.watchArgs.Debounce)
+			}
+		case err := <-watchErrors:
+			if errors.Is(err, fsnotify.ErrEventOverflow) {
+				// events were lost, every watched repository could have changed
+				for repositoryPath, repository := range w.repositories {
+					if !repository.polled {
+						pending[repositoryPath] = true
+					}
+				}
+				debounce.Reset(w.watchArgs.Debounce)
+				continue
+			}
+			w.reportError(err)
+		case <-poll.C:
+			for repositoryPath, repository := range w.repositories {
+				if !repository.polled {
+					continue
+				}
+				fingerprint, err := repository.currentFingerprint()
+				if err == nil && fingerprint != repository.fingerprint {
+					repository.fingerprint = fingerprint
+					pending[repositoryPath] = true
+					debounce.Reset(w.watchArgs.Debounce)
+				}
+			}
+		case records := <-refreshes:
+			handle(w.update(records))
+		case <-debounce.C:
+			records := w.check(slices.Collect(maps.Keys(pending)), &localAssayer, &localFetcher)
+			clear(pending)
+			handle(w.update(records))
+		}
+	}
+}
+
+// watch adds the git directory, its refs and every worktree directory to the watcher,
+// nothing of the repository stays watched if one of them cannot be added
+func (w *watchState) watch(repository *watchedRepository) error {
+	err := w.add(repository.gitDir, repository.path)
+	if err == nil {
+		err = w.addRefs(filepath.Join(repository.gitDir, "refs"), repository.path)
+	}
+	if err == nil {
+		err = w.addWorktree(repository.path, repository.path)
+	}
+	if err != nil {
+		w.unwatch(repository.path)
+	}
+	return err
+}
+
+// unwatch removes every watched directory of the repository from the watcher
+func (w *watchState) unwatch(repositoryPath string) {
+	for directory, owner := range w.owners {
+		if owner == repositoryPath {
+			_ = w.watcher.Remove(directory)
+			delete(w.owners, directory)
+		}
+	}
+}
+
+// poll checks the repository by its fingerprint instead of watching it
+func (w *watchState) poll(repository *watchedRepository) {
+	repository.polled = true
+	repository.fingerprint, _ = repository.currentFingerprint()
+}
+
+func (w *watchState) add(directory, repositoryPath string) error {
+	err := w.watcher.Add(directory)
+	if err != nil {
+		return fmt.Errorf("cannot watch %s\n%s", directory, err)
+	}
+	w.owners[directory] = repositoryPath
+	return nil
+}
+
+// addRefs watches the directory of refs and its subdirectories
+func (w *watchState) addRefs(directory, repositoryPath string) error {
+	return filepath.WalkDir(directory, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		return w.add(path, repositoryPath)
+	})
+}
+
+// addWorktree watches the worktree directory and its subdirectories,
+// except git directories, nested repositories and ignored directories
+func (w *watchState) addWorktree(directory, repositoryPath string) error {
+	return cache.WorktreeDirectories(repositoryPath, directory, func(path string) error {
+		return w.add(path, repositoryPath)
+	})
+}
+
+// handleEvent returns the path of the changed repository, or empty if the event changes nothing
+func (w *watchState) handleEvent(event fsnotify.Event) string {
+	if event.Op == fsnotify.Chmod {
+		return ""
+	}
+	repositoryPath, ok := w.owners[filepath.Dir(event.Name)]
+	if !ok {
+		return ""
+	}
+	if event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename) {
+		delete(w.owners, event.Name)
+	}
+	if event.Has(fsnotify.Create) {
+		info, err := os.Stat(event.Name)
+		if err == nil && info.IsDir() && filepath.Base(event.Name) != ".git" {
+			repository := w.repositories[repositoryPath]
+			if within(event.Name, repository.gitDir) {
+				err = w.addRefs(event.Name, repositoryPath)
+			} else {
+				err = w.addWorktree(event.Name, repositoryPath)
+			}
+			if err != nil {
+				// a partially watched repository would miss changes, it is polled instead
+				w.reportError(err)
+				w.unwatch(repositoryPath)
+				w.poll(repository)
+			}
+		}
+	}
+	return repositoryPath
+}
+
+// currentFingerprint is the state of a polled repository, files tracked in the index are listed
+// again only when the index changes
+func (r *watchedRepository) currentFingerprint() (string, error) {
+	return r.fingerprinter.Fingerprint(
+		r.path,
+		filepath.Join(r.gitDir, "FETCH_HEAD"),
+		filepath.Join(r.gitDir, "refs", "heads"),
+		filepath.Join(r.gitDir, "refs", "tags"),
+	)
+}
+
+// check checks repositories concurrently and returns their records
+func (w *watchState) check(
+	repositoryPaths []string,
+	assayer *check.Assayer,
+	fetcher *check.FetcherChecker,
+) []watchRecord {
+	records := make([]watchRecord, len(repositoryPaths))
+	var wg sync.WaitGroup
+	for i, repositoryPath := range repositoryPaths {
+		repository := w.repositories[repositoryPath]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			started := time.Now()
+			verdicts := make(chan types.Response, 100)
+			go func() {
+				assayer.CheckRepository(repository.directory, repository.repository, verdicts, &w.args, fetcher)
+				close(verdicts)
+			}()
+			record := watchRecord{
+				Repository: types.RepoName(types.NewUnmodified(repository.directory, repository.repository), w.detailed),
+				Path:       repository.path,
+				Verdicts:   []watchVerdict{},
+			}
+			for response := range verdicts {
+				if response.Err != nil {
+					record.Error = strings.ReplaceAll(response.Err.Error(), "\n", ": ")
+					continue
+				}
+				record.verdicts = append(record.verdicts, response.Verdict)
+				kind, details := describeVerdict(response.Verdict, w.args.Files)
+				if kind != "" {
+					record.Verdicts = append(record.Verdicts, watchVerdict{Kind: kind, Details: details})
+				}
+			}
+			// checkers can find verdicts in any order, sorting avoids reporting unchanged repositories
+			slices.SortFunc(record.Verdicts, func(a, b watchVerdict) int {
+				return cmp.Or(strings.Compare(a.Kind, b.Kind), strings.Compare(a.Details, b.Details))
+			})
+			record.Time = started
+			records[i] = record
+		}()
+	}
+	wg.Wait()
+	return records
+}
+
+// update stores records and returns those whose verdicts changed,
+// records of checks started before the stored one are dropped, so slow refreshes do not overwrite newer results
+func (w *watchState) update(records []watchRecord) []watchRecord {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	var changed []watchRecord
+	for _, record := range records {
+		previous, known := w.results[record.Path]
+		if known && record.Time.Before(previous.Time) {
+			continue
+		}
+		if known && previous.Error == record.Error && slices.Equal(previous.Verdicts, record.Verdicts) {
+			previous.Time = record.Time
+			w.results[record.Path] = previous
+			continue
+		}
+		// clean repositories are not reported until they had findings
+		if !known && record.Error == "" && len(record.Verdicts) == 0 {
+			w.results[record.Path] = record
+			continue
+		}
+		w.results[record.Path] = record
+		changed = append(changed, record)
+	}
+	slices.SortFunc(changed, func(a, b watchRecord) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+	return changed
+}
+
+// report prints changed records as lines of JSON, or the summary of all repositories
+func (w *watchState) report(changed []watchRecord) {
+	if w.watchArgs.JSON {
+		encoder := json.NewEncoder(os.Stdout)
+		for _, record := range changed {
+			_ = encoder.Encode(record)
+		}
+		return
+	}
+	for _, record := range changed {
+		if record.Error != "" {
+			_, _ = fmt.Fprintf(os.Stderr, "error checking %s: %s\n", record.Repository, record.Error)
+		}
+	}
+
+	var records []watchRecord
+	kinds := make(map[string]int)
+	var kindOrder []string
+	for _, record := range w.results {
+		if len(record.Verdicts) == 0 {
+			continue
+		}
+		records = append(records, record)
+		for _, kind := range record.kinds() {
+			if kinds[kind] == 0 {
+				kindOrder = append(kindOrder, kind)
+			}
+			kinds[kind] += 1
+		}
+	}
+	slices.SortFunc(records, func(a, b watchRecord) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+	slices.Sort(kindOrder)
+
+	counts := make([]string, 0, len(kindOrder))
+	for _, kind := range kindOrder {
+		counts = append(counts, fmt.Sprintf("%s %d", kind, kinds[kind]))
+	}
+	summary := fmt.Sprintf("%d repositories with findings", len(records))
+	if len(counts) > 0 {
+		summary += " (" + strings.Join(counts, ", ") + ")"
+	}
+	summary += " at " + time.Now().Format(time.TimeOnly)
+
+	// a terminal shows the current state, otherwise every update is a single line
+	if !w.terminal {
+		fmt.Println(summary)
+		return
+	}
+	fmt.Print("\x1b[H\x1b[2J")
+	for _, record := range records {
+		_ = reportRepoResult(record.Repository, strings.Join(record.kinds(), ", "), "", false)
+	}
+	fmt.Println(summary)
+}
+
+func (w *watchState) reportError(err error) {
+	if w.watchArgs.JSON {
+		_ = json.NewEncoder(os.Stdout).Encode(watchRecord{
+			Time:     time.Now(),
+			Verdicts: []watchVerdict{},
+			Error:    strings.ReplaceAll(err.Error(), "\n", ": "),
+		})
+		return
+	}
+	_, _ = fmt.Fprintln(os.Stderr, err)
+}
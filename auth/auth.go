@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// Provider picks credentials for remotes by URL scheme and host.
+// SSH remotes use ssh-agent, explicitly given keys and IdentityFile entries of ~/.ssh/config,
+// HTTP remotes use ASSAYER_TOKEN_<HOST> environment variables and git credential helpers.
+type Provider struct {
+	sshKeys []string
+
+	// methods are looked up under mutex, each entry is resolved once outside of it
+	methods map[string]*authEntry
+	mutex   sync.Mutex
+
+	// agent is connected on first use and shared by ssh remotes until Finish,
+	// its signers sign through the connection
+	agent      agent.Agent
+	agentConn  net.Conn
+	agentMutex sync.Mutex
+}
+
+type authEntry struct {
+	once   sync.Once
+	method transport.AuthMethod
+	err    error
+}
+
+func NewProvider(sshKeys []string) *Provider {
+	return &Provider{
+		sshKeys: sshKeys,
+		methods: make(map[string]*authEntry),
+	}
+}
+
+// AuthFor returns authentication method for remote url, nil means anonymous access
+func (p *Provider) AuthFor(remoteUrl string) (transport.AuthMethod, error) {
+	endpoint, err := transport.NewEndpoint(remoteUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	// credentials of the full endpoint, helpers may answer per port and path
+	key := endpoint.String()
+	p.mutex.Lock()
+	entry, found := p.methods[key]
+	if !found {
+		entry = &authEntry{}
+		p.methods[key] = entry
+	}
+	p.mutex.Unlock()
+
+	entry.once.Do(func() {
+		switch endpoint.Protocol {
+		case "ssh":
+			entry.method = p.sshAuth(endpoint)
+		case "http", "https":
+			entry.method, entry.err = httpAuth(endpoint)
+		}
+	})
+	return entry.method, entry.err
+}
+
+// Finish closes the connection to ssh-agent
+func (p *Provider) Finish() {
+	p.agentMutex.Lock()
+	defer p.agentMutex.Unlock()
+	p.closeAgent()
+}
+
+// TokenEnvName is the name of the environment variable holding token for host,
+// e.g. ASSAYER_TOKEN_GITLAB_EXAMPLE_COM for gitlab.example.com
+func TokenEnvName(host string) string {
+	name := strings.Map(func(char rune) rune {
+		if (char >= 'A' && char <= 'Z') || (char >= '0' && char <= '9') {
+			return char
+		}
+		return '_'
+	}, strings.ToUpper(host))
+	return "ASSAYER_TOKEN_" + name
+}
@@ -0,0 +1,109 @@
+package auth
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+)
+
+func TestTokenEnvName(t *testing.T) {
+	if name := TokenEnvName("gitlab.example.com"); name != "ASSAYER_TOKEN_GITLAB_EXAMPLE_COM" {
+		t.Errorf(`Unexpected env name %s`, name)
+	}
+	if name := TokenEnvName("git-server"); name != "ASSAYER_TOKEN_GIT_SERVER" {
+		t.Errorf(`Unexpected env name %s`, name)
+	}
+}
+
+func TestHttpToken(t *testing.T) {
+	t.Setenv("ASSAYER_TOKEN_GITLAB_EXAMPLE_COM", "secret")
+	t.Setenv("ASSAYER_TOKEN_GITHUB_COM", "user:secret")
+	provider := NewProvider(nil)
+
+	method, err := provider.AuthFor("https://gitlab.example.com/group/sub/repo.git")
+	if err != nil {
+		t.Fatal(err)
+	}
+	basicAuth, ok := method.(*http.BasicAuth)
+	if !ok || basicAuth.Username != tokenUser || basicAuth.Password != "secret" {
+		t.Errorf(`Should use token from environment, got %v`, method)
+	}
+
+	method, err = provider.AuthFor("https://github.com/hov1417/assayer.git")
+	if err != nil {
+		t.Fatal(err)
+	}
+	basicAuth, ok = method.(*http.BasicAuth)
+	if !ok || basicAuth.Username != "user" || basicAuth.Password != "secret" {
+		t.Errorf(`Should use user and token from environment, got %v`, method)
+	}
+}
+
+func TestHttpCredentialsPerEndpoint(t *testing.T) {
+	directory := t.TempDir()
+	// the helper answers with the host and path it was asked for as password
+	helper := filepath.Join(directory, "helper.sh")
+	err := os.WriteFile(helper, []byte(`#!/bin/sh
+[ "$1" = get ] || exit 0
+while IFS== read -r key value; do
+  case "$key" in
+    host) host="$value" ;;
+    path) path="$value" ;;
+  esac
+done
+echo username=user
+echo "password=$host/$path"
+`), 0o755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := filepath.Join(directory, "gitconfig")
+	err = os.WriteFile(config, []byte("[credential]\n\thelper = "+helper+"\n\tuseHttpPath = true\n"), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GIT_CONFIG_GLOBAL", config)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	provider := NewProvider(nil)
+
+	for _, test := range []struct{ url, password string }{
+		{"https://git.example.com/first.git", "git.example.com/first.git"},
+		{"https://git.example.com/second.git", "git.example.com/second.git"},
+		{"https://git.example.com:8443/first.git", "git.example.com:8443/first.git"},
+		{"https://git.example.com/first.git", "git.example.com/first.git"},
+	} {
+		method, err := provider.AuthFor(test.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		basicAuth, ok := method.(*http.BasicAuth)
+		if !ok || basicAuth.Password != test.password {
+			t.Errorf(`Should use credentials of %s, got %v`, test.url, method)
+		}
+	}
+}
+
+func TestSshUser(t *testing.T) {
+	provider := NewProvider(nil)
+
+	method, err := provider.AuthFor("login@server.com:group/repository.git")
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, ok := method.(*gitssh.PublicKeysCallback)
+	if !ok || keys.User != "login" {
+		t.Errorf(`Should use user from url, got %v`, method)
+	}
+
+	method, err = provider.AuthFor("ssh://server.com:12345/group/repository.git")
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, ok = method.(*gitssh.PublicKeysCallback)
+	if !ok || keys.User != "git" {
+		t.Errorf(`Should default to git user, got %v`, method)
+	}
+}
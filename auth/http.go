@@ -0,0 +1,73 @@
+package auth
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+)
+
+// tokenUser is used when token is given without a user, it is accepted by GitHub and GitLab
+const tokenUser = "oauth2"
+
+func httpAuth(endpoint *transport.Endpoint) (transport.AuthMethod, error) {
+	if token, found := os.LookupEnv(TokenEnvName(endpoint.Host)); found {
+		user, password, hasUser := strings.Cut(token, ":")
+		if !hasUser {
+			user, password = tokenUser, token
+		}
+		return &http.BasicAuth{Username: user, Password: password}, nil
+	}
+
+	if endpoint.Password != "" {
+		// credentials in the url are used by go-git itself
+		return nil, nil
+	}
+
+	user, password, found := credentialFill(endpoint)
+	if !found {
+		return nil, nil
+	}
+	return &http.BasicAuth{Username: user, Password: password}, nil
+}
+
+// credentialFill asks configured git credential helpers for credentials, never prompting
+func credentialFill(endpoint *transport.Endpoint) (string, string, bool) {
+	host := endpoint.Host
+	if endpoint.Port != 0 {
+		host = fmt.Sprintf("%s:%d", endpoint.Host, endpoint.Port)
+	}
+	input := fmt.Sprintf(
+		"protocol=%s\nhost=%s\npath=%s\n",
+		endpoint.Protocol,
+		host,
+		strings.TrimPrefix(endpoint.Path, "/"),
+	)
+	if endpoint.User != "" {
+		input += fmt.Sprintf("username=%s\n", endpoint.User)
+	}
+
+	command := exec.Command("git", "credential", "fill")
+	command.Stdin = strings.NewReader(input + "\n")
+	command.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0", "GIT_ASKPASS=", "SSH_ASKPASS=")
+	output, err := command.Output()
+	if err != nil {
+		return "", "", false
+	}
+
+	var user, password string
+	for _, line := range bytes.Split(output, []byte("\n")) {
+		key, value, _ := strings.Cut(string(line), "=")
+		switch key {
+		case "username":
+			user = value
+		case "password":
+			password = value
+		}
+	}
+	return user, password, password != ""
+}
@@ -0,0 +1,97 @@
+package auth
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	"github.com/kevinburke/ssh_config"
+	sshagent "github.com/xanzy/ssh-agent"
+	"golang.org/x/crypto/ssh"
+)
+
+var defaultIdentityFiles = []string{"~/.ssh/id_ed25519", "~/.ssh/id_ecdsa", "~/.ssh/id_rsa"}
+
+// sshAuth offers keys of ssh-agent and every readable unencrypted key file,
+// explicitly given keys are offered first
+func (p *Provider) sshAuth(endpoint *transport.Endpoint) transport.AuthMethod {
+	user := endpoint.User
+	if user == "" {
+		user = ssh_config.Get(endpoint.Host, "User")
+	}
+	if user == "" {
+		user = "git"
+	}
+
+	keyFiles := append([]string{}, p.sshKeys...)
+	keyFiles = append(keyFiles, ssh_config.GetAll(endpoint.Host, "IdentityFile")...)
+	keyFiles = append(keyFiles, defaultIdentityFiles...)
+
+	return &gitssh.PublicKeysCallback{
+		User: user,
+		Callback: func() ([]ssh.Signer, error) {
+			var signers []ssh.Signer
+			seen := make(map[string]bool)
+			for _, keyFile := range keyFiles {
+				keyFile = expandHome(keyFile)
+				if seen[keyFile] {
+					continue
+				}
+				seen[keyFile] = true
+				content, err := os.ReadFile(keyFile)
+				if err != nil {
+					continue
+				}
+				// encrypted keys are expected to be loaded in ssh-agent
+				signer, err := ssh.ParsePrivateKey(content)
+				if err != nil {
+					continue
+				}
+				signers = append(signers, signer)
+			}
+
+			return append(signers, p.agentSigners()...), nil
+		},
+	}
+}
+
+// agentSigners returns keys of ssh-agent, connecting to it on first use
+func (p *Provider) agentSigners() []ssh.Signer {
+	p.agentMutex.Lock()
+	defer p.agentMutex.Unlock()
+	if p.agent == nil {
+		agent, conn, err := sshagent.New()
+		if err != nil {
+			return nil
+		}
+		p.agent, p.agentConn = agent, conn
+	}
+	signers, err := p.agent.Signers()
+	if err != nil {
+		// the agent may have been restarted, the next call connects again
+		p.closeAgent()
+		return nil
+	}
+	return signers
+}
+
+func (p *Provider) closeAgent() {
+	if p.agentConn != nil {
+		_ = p.agentConn.Close()
+	}
+	p.agent, p.agentConn = nil, nil
+}
+
+func expandHome(path string) string {
+	rest, found := strings.CutPrefix(path, "~/")
+	if !found {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, rest)
+}
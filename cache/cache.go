@@ -0,0 +1,52 @@
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Directory returns assayer's cache directory, by default $XDG_CACHE_HOME/assayer
+func Directory() (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("cannot find cache directory\n%s", err)
+	}
+	return filepath.Join(cacheDir, "assayer"), nil
+}
+
+// writeJSON atomically replaces file with JSON encoding of value
+func writeJSON(file string, value any) error {
+	err := os.MkdirAll(filepath.Dir(file), 0o755)
+	if err != nil {
+		return err
+	}
+	temp, err := os.CreateTemp(filepath.Dir(file), filepath.Base(file)+".*")
+	if err != nil {
+		return err
+	}
+	err = json.NewEncoder(temp).Encode(value)
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(temp.Name())
+		return err
+	}
+	return os.Rename(temp.Name(), file)
+}
+
+// SocketPath returns the default path of the daemon socket, $XDG_RUNTIME_DIR/assayer.sock
+// or daemon.sock in the cache directory
+func SocketPath() (string, error) {
+	runtimeDir := os.Getenv("XDG_RUNTIME_DIR")
+	if runtimeDir != "" {
+		return filepath.Join(runtimeDir, "assayer.sock"), nil
+	}
+	directory, err := Directory()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(directory, "daemon.sock"), nil
+}
@@ -0,0 +1,54 @@
+package cache
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+func loadFetchTimes(file string) map[string]time.Time {
+	fetchTimes := make(map[string]time.Time)
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return fetchTimes
+	}
+	if json.Unmarshal(content, &fetchTimes) != nil {
+		return make(map[string]time.Time)
+	}
+	return fetchTimes
+}
+
+// LastFetch returns the time remote was last fetched, either by assayer or by git itself,
+// which is known from FETCH_HEAD modification time, zero time if it was never fetched
+func LastFetch(repositoryPath, remote string) time.Time {
+	var lastFetch time.Time
+	gitDir, err := GitDir(repositoryPath)
+	if err == nil {
+		info, err := os.Stat(filepath.Join(gitDir, "FETCH_HEAD"))
+		if err == nil {
+			lastFetch = info.ModTime()
+		}
+	}
+
+	file, err := repositoryFile("fetch", repositoryPath)
+	if err != nil {
+		return lastFetch
+	}
+	recorded := loadFetchTimes(file)[remote]
+	if recorded.After(lastFetch) {
+		return recorded
+	}
+	return lastFetch
+}
+
+// RecordFetch records the time of a fetch by assayer, go-git does not write FETCH_HEAD
+func RecordFetch(repositoryPath, remote string, fetchTime time.Time) error {
+	file, err := repositoryFile("fetch", repositoryPath)
+	if err != nil {
+		return err
+	}
+	fetchTimes := loadFetchTimes(file)
+	fetchTimes[remote] = fetchTime
+	return writeJSON(file, fetchTimes)
+}
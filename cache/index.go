@@ -0,0 +1,144 @@
+package cache
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+// DirectoryRecord is a directory as it was seen during the last scan
+type DirectoryRecord struct {
+	ModTime     int64    `json:"modTime"`
+	Directories []string `json:"directories"`
+}
+
+// RootRecord is the result of the last scan of a root directory,
+// paths are relative to the root
+type RootRecord struct {
+	ScannedAt    time.Time                  `json:"scannedAt"`
+	Directories  map[string]DirectoryRecord `json:"directories"`
+	Repositories []string                   `json:"repositories"`
+}
+
+// Index holds discovered repositories of every scanned root,
+// it is persisted in index.json of the cache directory
+type Index struct {
+	Roots map[string]*RootRecord `json:"roots"`
+
+	file  string
+	mutex sync.Mutex
+}
+
+// LoadIndex reads the index from the cache directory,
+// missing or unreadable index is treated as empty
+func LoadIndex() (*Index, error) {
+	directory, err := Directory()
+	if err != nil {
+		return nil, err
+	}
+	index := &Index{
+		Roots: make(map[string]*RootRecord),
+		file:  filepath.Join(directory, "index.json"),
+	}
+	content, err := os.ReadFile(index.file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return index, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if json.Unmarshal(content, index) != nil || index.Roots == nil {
+		index.Roots = make(map[string]*RootRecord)
+	}
+	return index, nil
+}
+
+func (i *Index) Save() error {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	return writeJSON(i.file, i)
+}
+
+// Scan starts incremental scan of root, if rescan is set previous record is ignored
+func (i *Index) Scan(root string, rescan bool) (*RootScan, error) {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	previous := i.Roots[absRoot]
+	if rescan || previous == nil {
+		previous = &RootRecord{}
+	}
+	return &RootScan{
+		index:    i,
+		root:     absRoot,
+		previous: previous,
+		current: &RootRecord{
+			Directories: make(map[string]DirectoryRecord),
+		},
+	}, nil
+}
+
+// RootScan lists directories of a root reusing the previous record
+// for directories which modification time has not changed
+type RootScan struct {
+	index    *Index
+	root     string
+	previous *RootRecord
+	current  *RootRecord
+	mutex    sync.Mutex
+}
+
+// Directories returns names of subdirectories of path,
+// the directory is read only if it changed since the last scan
+func (s *RootScan) Directories(dirFs fs.FS, path string) ([]string, error) {
+	info, err := fs.Stat(dirFs, path)
+	if err != nil {
+		return nil, err
+	}
+	modTime := info.ModTime().UnixNano()
+
+	record, found := s.previous.Directories[path]
+	if !found || record.ModTime != modTime {
+		readDir, err := fs.ReadDir(dirFs, path)
+		if err != nil {
+			return nil, err
+		}
+		record = DirectoryRecord{ModTime: modTime, Directories: make([]string, 0)}
+		for _, entry := range readDir {
+			if entry.IsDir() {
+				record.Directories = append(record.Directories, entry.Name())
+			}
+		}
+	}
+
+	s.mutex.Lock()
+	s.current.Directories[path] = record
+	s.mutex.Unlock()
+	return record.Directories, nil
+}
+
+func (s *RootScan) AddRepository(repository string) {
+	s.mutex.Lock()
+	s.current.Repositories = append(s.current.Repositories, repository)
+	s.mutex.Unlock()
+}
+
+// Finish replaces the root record in the index, directories not visited during the scan are dropped
+func (s *RootScan) Finish() {
+	s.mutex.Lock()
+	s.current.ScannedAt = time.Now()
+	slices.Sort(s.current.Repositories)
+	s.mutex.Unlock()
+
+	s.index.mutex.Lock()
+	s.index.Roots[s.root] = s.current
+	s.index.mutex.Unlock()
+}
@@ -0,0 +1,197 @@
+package cache
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/format/index"
+)
+
+// StatusRecord is the cached worktree status of a repository
+type StatusRecord struct {
+	Fingerprint string     `json:"fingerprint"`
+	Status      git.Status `json:"status"`
+}
+
+// gitFiles are files in the git directory that change whenever status could change,
+// info/exclude and config (core.excludesFile) change which files are untracked
+var gitFiles = []string{"index", "HEAD", "packed-refs", "logs/HEAD", "logs/refs/stash", "info/exclude", "config"}
+
+// Fingerprint summarizes the state of a repository by modification times and sizes of
+// index, HEAD, checked out branch, packed-refs, stash reflog, info/exclude and config,
+// extraFiles outside the repository, such as the global excludes file, and the worktree.
+// Worktree directories change their modification times when entries are added or removed,
+// files edited in place are covered by stats of files tracked in the index and of .gitignore files.
+// Ignored directories and nested repositories are not walked.
+func Fingerprint(repositoryPath string, extraFiles ...string) (string, error) {
+	var fingerprinter Fingerprinter
+	return fingerprinter.Fingerprint(repositoryPath, extraFiles...)
+}
+
+// Fingerprinter fingerprints a repository repeatedly, as polling does,
+// files tracked in the index are read again only when the index changes
+type Fingerprinter struct {
+	index   string
+	tracked []string
+}
+
+// Fingerprint summarizes the state of a repository as the Fingerprint function does
+func (f *Fingerprinter) Fingerprint(repositoryPath string, extraFiles ...string) (string, error) {
+	gitDir, err := GitDir(repositoryPath)
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.New()
+	files := append([]string{}, gitFiles...)
+	head, err := os.ReadFile(filepath.Join(gitDir, "HEAD"))
+	if err == nil && strings.HasPrefix(string(head), "ref: ") {
+		files = append(files, strings.TrimSpace(strings.TrimPrefix(string(head), "ref: ")))
+	}
+	for _, file := range files {
+		writeFileStat(hash, file, filepath.Join(gitDir, file))
+	}
+	for _, file := range extraFiles {
+		writeFileStat(hash, file, file)
+	}
+
+	tracked, err := f.trackedFiles(gitDir)
+	if err != nil {
+		return "", err
+	}
+	for _, file := range tracked {
+		writeFileStat(hash, file, filepath.Join(repositoryPath, filepath.FromSlash(file)))
+	}
+	err = WorktreeDirectories(repositoryPath, repositoryPath, func(directory string) error {
+		info, err := os.Stat(directory)
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(repositoryPath, directory)
+		if err != nil {
+			return err
+		}
+		_, _ = fmt.Fprintf(hash, "%s/ %d\n", relPath, info.ModTime().UnixNano())
+		writeFileStat(hash, filepath.Join(relPath, ".gitignore"), filepath.Join(directory, ".gitignore"))
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// trackedFiles returns paths of files tracked in the index, decoding it only if it changed since the last call
+func (f *Fingerprinter) trackedFiles(gitDir string) ([]string, error) {
+	indexFile := filepath.Join(gitDir, "index")
+	info, err := os.Stat(indexFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	indexStat := fmt.Sprintf("%d %d", info.ModTime().UnixNano(), info.Size())
+	if indexStat == f.index {
+		return f.tracked, nil
+	}
+
+	file, err := os.Open(indexFile)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var idx index.Index
+	err = index.NewDecoder(bufio.NewReader(file)).Decode(&idx)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read index\n%s", err)
+	}
+	tracked := make([]string, 0, len(idx.Entries))
+	for _, entry := range idx.Entries {
+		tracked = append(tracked, entry.Name)
+	}
+	f.index, f.tracked = indexStat, tracked
+	return tracked, nil
+}
+
+func writeFileStat(writer io.Writer, name, path string) {
+	info, err := os.Stat(path)
+	if err != nil {
+		_, _ = fmt.Fprintf(writer, "%s missing\n", name)
+		return
+	}
+	_, _ = fmt.Fprintf(writer, "%s %d %d\n", name, info.ModTime().UnixNano(), info.Size())
+}
+
+// GitDir resolves the git directory of a worktree, following "gitdir:" files
+func GitDir(repositoryPath string) (string, error) {
+	gitPath := filepath.Join(repositoryPath, ".git")
+	info, err := os.Stat(gitPath)
+	if err != nil {
+		return "", err
+	}
+	if info.IsDir() {
+		return gitPath, nil
+	}
+	content, err := os.ReadFile(gitPath)
+	if err != nil {
+		return "", err
+	}
+	gitDir, found := strings.CutPrefix(strings.TrimSpace(string(content)), "gitdir: ")
+	if !found {
+		return "", fmt.Errorf("unknown .git file format in %s", repositoryPath)
+	}
+	if !filepath.IsAbs(gitDir) {
+		gitDir = filepath.Join(repositoryPath, gitDir)
+	}
+	return gitDir, nil
+}
+
+// repositoryFile is the path of a per repository cache file in kind subdirectory
+func repositoryFile(kind, repositoryPath string) (string, error) {
+	directory, err := Directory()
+	if err != nil {
+		return "", err
+	}
+	absPath, err := filepath.Abs(repositoryPath)
+	if err != nil {
+		return "", err
+	}
+	name := sha256.Sum256([]byte(absPath))
+	return filepath.Join(directory, kind, hex.EncodeToString(name[:16])+".json"), nil
+}
+
+// LoadStatus returns the cached status if it was recorded with the same fingerprint
+func LoadStatus(repositoryPath, fingerprint string) (git.Status, bool) {
+	file, err := repositoryFile("status", repositoryPath)
+	if err != nil {
+		return nil, false
+	}
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, false
+	}
+	var record StatusRecord
+	if json.Unmarshal(content, &record) != nil || record.Fingerprint != fingerprint {
+		return nil, false
+	}
+	return record.Status, record.Status != nil
+}
+
+func SaveStatus(repositoryPath, fingerprint string, status git.Status) error {
+	file, err := repositoryFile("status", repositoryPath)
+	if err != nil {
+		return err
+	}
+	return writeJSON(file, StatusRecord{Fingerprint: fingerprint, Status: status})
+}
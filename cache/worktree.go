@@ -0,0 +1,109 @@
+package cache
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+)
+
+// WorktreeDirectories calls visit with the directory and its subdirectories in the worktree of the repository,
+// except git directories, nested repositories and directories ignored by info/exclude or .gitignore files
+func WorktreeDirectories(repositoryPath, directory string, visit func(directory string) error) error {
+	gitDir, err := GitDir(repositoryPath)
+	if err != nil {
+		return err
+	}
+	patterns, err := readIgnorePatterns(filepath.Join(gitDir, "info", "exclude"), nil)
+	if err != nil {
+		return err
+	}
+	relPath, err := filepath.Rel(repositoryPath, directory)
+	if err != nil {
+		return err
+	}
+	var path []string
+	if relPath != "." {
+		path = strings.Split(filepath.ToSlash(relPath), "/")
+	}
+	// patterns of parent directories apply to the directory too
+	for i := range path {
+		if path[i] == ".git" {
+			return nil
+		}
+		parentPatterns, err := readIgnorePatterns(filepath.Join(repositoryPath, filepath.Join(path[:i]...), ".gitignore"), path[:i])
+		if err != nil {
+			return err
+		}
+		patterns = append(patterns, parentPatterns...)
+		if gitignore.NewMatcher(patterns).Match(path[:i+1], true) {
+			return nil
+		}
+	}
+	if len(path) > 0 && isRepository(directory) {
+		return nil
+	}
+	return walkWorktree(repositoryPath, path, patterns, visit)
+}
+
+func walkWorktree(repositoryPath string, path []string, patterns []gitignore.Pattern, visit func(directory string) error) error {
+	directory := filepath.Join(repositoryPath, filepath.Join(path...))
+	err := visit(directory)
+	if err != nil {
+		return err
+	}
+	entries, err := os.ReadDir(directory)
+	if err != nil {
+		return err
+	}
+	ignorePatterns, err := readIgnorePatterns(filepath.Join(directory, ".gitignore"), path)
+	if err != nil {
+		return err
+	}
+	patterns = append(slices.Clip(patterns), ignorePatterns...)
+	matcher := gitignore.NewMatcher(patterns)
+
+	for _, entry := range entries {
+		if !entry.IsDir() || entry.Name() == ".git" {
+			continue
+		}
+		entryPath := append(slices.Clip(path), entry.Name())
+		// nested repositories are single untracked entries of their parent
+		if matcher.Match(entryPath, true) || isRepository(filepath.Join(directory, entry.Name())) {
+			continue
+		}
+		err = walkWorktree(repositoryPath, entryPath, patterns, visit)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func isRepository(directory string) bool {
+	_, err := os.Lstat(filepath.Join(directory, ".git"))
+	return err == nil
+}
+
+// readIgnorePatterns reads patterns of an ignore file in the directory path, a missing file has none
+func readIgnorePatterns(file string, path []string) ([]gitignore.Pattern, error) {
+	content, err := os.ReadFile(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var patterns []gitignore.Pattern
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if !strings.HasPrefix(line, "#") && strings.TrimSpace(line) != "" {
+			patterns = append(patterns, gitignore.ParsePattern(line, path))
+		}
+	}
+	return patterns, nil
+}
@@ -1,10 +1,10 @@
 package check
 
 import (
-	"errors"
 	"fmt"
 	"path/filepath"
 	"reflect"
+	"strings"
 
 	"github.com/go-git/go-git/v5"
 	"github.com/hov1417/assayer/arguments"
@@ -15,12 +15,16 @@ type Assayer struct {
 	checkers []Checker
 }
 
-func NewAssayer(arguments arguments.Arguments) Assayer {
+func NewAssayer(arguments arguments.Arguments, fetch *FetcherChecker) Assayer {
 	checkers := make([]Checker, 0)
 
 	checkers = append(checkers, NewWorkTreeChecker(arguments))
 	checkers = append(checkers, NewStashChecker(arguments))
-	checkers = append(checkers, NewBranchChecker(arguments))
+	branchChecker := NewBranchChecker(arguments)
+	if branchChecker != nil && fetch != nil && fetch.Probe {
+		branchChecker.probe = fetch
+	}
+	checkers = append(checkers, branchChecker)
 
 	filteredSlice := make([]Checker, 0, len(checkers))
 	for _, item := range checkers {
@@ -49,6 +53,9 @@ func (a *Assayer) CheckRepository(
 		verdicts <- types.Response{Err: fmt.Errorf("error opening git repository %s\n%s", repository, err)}
 		return
 	}
+	if args.SkipIgnored && IsIgnored(repo) {
+		return
+	}
 
 	remotes, err := repo.Remotes()
 	if err != nil {
@@ -62,35 +69,52 @@ func (a *Assayer) CheckRepository(
 		}
 		// Fetch always uses first url of remote
 		fetchUrl := urls[0]
-		if fetch.NeedsFetch(fetchUrl) {
-			err = repo.Fetch(&git.FetchOptions{
-				RemoteName: remote.Config().Name,
-			})
-			if errors.Is(err, git.NoErrAlreadyUpToDate) {
-				continue
-			}
+		// probed remotes are listed by the branch checker instead of fetched
+		if !fetch.Probe && fetch.NeedsFetch(remote.Config().Name, fetchUrl) &&
+			!fetch.IsFresh(fullPath, remote.Config().Name) {
+			err = fetch.Fetch(repo, fullPath, remote.Config().Name, fetchUrl)
 			if err != nil {
-				verdicts <- types.Response{Err: fmt.Errorf("error fetching remote %s\n%s", remote.Config().Name, err)}
+				verdicts <- types.Response{Err: err}
 				return
 			}
 		}
 	}
 
 	foundVerdict := false
+	failed := false
 	for _, checker := range a.checkers {
 		for v := range checker.Check(directory, repository, repo) {
 			if v.Err != nil {
 				v.Err = fmt.Errorf("error in checker %s:\n%s", checker.ToString(), v.Err)
+				failed = true
 			}
 			verdicts <- v
-			if !args.Deep {
+			// files mode lists every file of the worktree check
+			if !args.Deep && !args.Files {
 				return
 			}
 			foundVerdict = true
 		}
+		if foundVerdict && !args.Deep {
+			return
+		}
 	}
-	if !foundVerdict && args.Unmodified {
+	// a repository which could not be checked is not known to be unmodified
+	if !foundVerdict && !failed && args.Unmodified {
 		verdicts <- types.Response{Verdict: types.NewUnmodified(directory, repository)}
 	}
 
 }
+
+// IsIgnored reports whether the repository is ignored with `git config assayer.ignore true`
+func IsIgnored(repo *git.Repository) bool {
+	local, err := repo.Config()
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(local.Raw.Section("assayer").Option("ignore")) {
+	case "true", "yes", "on", "1":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,48 @@
+package check
+
+import (
+	"errors"
+	"iter"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/hov1417/assayer/arguments"
+	"github.com/hov1417/assayer/types"
+)
+
+type failingChecker struct{}
+
+func (f failingChecker) Check(string, string, *git.Repository) iter.Seq[types.Response] {
+	return func(yield func(types.Response) bool) {
+		yield(types.Response{Err: errors.New("cannot check")})
+	}
+}
+
+func (f failingChecker) ToString() string {
+	return "failingChecker"
+}
+
+func TestFailedRepositoryIsNotUnmodified(t *testing.T) {
+	repository := newTestRepository(t)
+	for _, deep := range []bool{false, true} {
+		assayer := Assayer{checkers: []Checker{failingChecker{}, failingChecker{}}}
+		verdicts := make(chan types.Response, 10)
+		args := arguments.Arguments{Deep: deep, Unmodified: true}
+		assayer.CheckRepository(
+			filepath.Dir(repository), filepath.Base(repository), verdicts, &args, &FetcherChecker{},
+		)
+		close(verdicts)
+		failures := 0
+		for response := range verdicts {
+			if response.Err != nil {
+				failures++
+			} else {
+				t.Errorf("deep %v: unexpected verdict %T", deep, response.Verdict)
+			}
+		}
+		if failures == 0 {
+			t.Errorf("deep %v: the checker error was not reported", deep)
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package check
+
+import (
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/hov1417/assayer/arguments"
+)
+
+// Backend provides repository data to WorkTreeChecker and BranchChecker,
+// path is the full path to the repository worktree
+type Backend interface {
+	Status(path string, repo *git.Repository) (git.Status, error)
+	LocalBranches(path string, repo *git.Repository) (map[string]plumbing.Hash, error)
+	RemoteBranches(path string, repo *git.Repository) ([]*plumbing.Reference, error)
+	IsAncestor(path string, repo *git.Repository, ancestor, descendant plumbing.Hash) (bool, error)
+	// FastForwardBranch moves a branch which is not checked out, it fails if the branch is not at from
+	FastForwardBranch(path string, repo *git.Repository, branch string, from, to plumbing.Hash) error
+	// FastForwardHead moves the checked out branch and updates its clean worktree
+	FastForwardHead(path string, repo *git.Repository, to plumbing.Hash) error
+	ToString() string
+}
+
+func NewBackend(backendType arguments.BackendType) Backend {
+	if backendType == arguments.BackendGit {
+		return &GitBackend{}
+	}
+	return &GoGitBackend{}
+}
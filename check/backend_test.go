@@ -0,0 +1,284 @@
+package check
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+var backends = []Backend{&GoGitBackend{}, &GitBackend{}}
+
+func gitCommand(directory string, args ...string) *exec.Cmd {
+	command := exec.Command("git", append([]string{"-C", directory}, args...)...)
+	command.Env = append(
+		os.Environ(),
+		"GIT_CONFIG_GLOBAL="+os.DevNull,
+		"GIT_CONFIG_NOSYSTEM=1",
+		"GIT_AUTHOR_NAME=assayer",
+		"GIT_AUTHOR_EMAIL=assayer@example.com",
+		"GIT_COMMITTER_NAME=assayer",
+		"GIT_COMMITTER_EMAIL=assayer@example.com",
+	)
+	return command
+}
+
+func runGitCommand(t *testing.T, directory string, args ...string) {
+	t.Helper()
+	output, err := gitCommand(directory, args...).CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v failed: %s\n%s", args, err, output)
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	err := os.MkdirAll(filepath.Dir(path), 0o755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(path, []byte(content), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func newTestRepository(t *testing.T) string {
+	t.Helper()
+	directory := filepath.Join(t.TempDir(), "origin")
+	runGitCommand(t, t.TempDir(), "init", "--initial-branch=master", directory)
+	writeFile(t, filepath.Join(directory, "modified.txt"), "commit")
+	writeFile(t, filepath.Join(directory, "deleted.txt"), "commit")
+	writeFile(t, filepath.Join(directory, "dir", "file.txt"), "commit")
+	runGitCommand(t, directory, "add", ".")
+	runGitCommand(t, directory, "commit", "-m", "initial commit")
+	return directory
+}
+
+func TestBackendStatus(t *testing.T) {
+	directory := newTestRepository(t)
+	writeFile(t, filepath.Join(directory, "modified.txt"), "changed")
+	writeFile(t, filepath.Join(directory, "added.txt"), "added")
+	runGitCommand(t, directory, "add", "added.txt")
+	err := os.Remove(filepath.Join(directory, "deleted.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(directory, "dir", "untracked.txt"), "untracked")
+
+	expected := git.Status{
+		"modified.txt":      {Staging: git.Unmodified, Worktree: git.Modified},
+		"added.txt":         {Staging: git.Added, Worktree: git.Unmodified},
+		"deleted.txt":       {Staging: git.Unmodified, Worktree: git.Deleted},
+		"dir/untracked.txt": {Staging: git.Untracked, Worktree: git.Untracked},
+	}
+
+	repo, err := git.PlainOpen(directory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, backend := range backends {
+		t.Run(backend.ToString(), func(t *testing.T) {
+			status, err := backend.Status(directory, repo)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(status) != len(expected) {
+				t.Errorf("expected %d changed files, got %v", len(expected), status)
+			}
+			for file, fileStatus := range expected {
+				actual, ok := status[file]
+				if !ok {
+					t.Errorf("file %s is missing from status", file)
+					continue
+				}
+				if actual.Staging != fileStatus.Staging || actual.Worktree != fileStatus.Worktree {
+					t.Errorf("file %s expected status %c%c, got %c%c",
+						file, fileStatus.Staging, fileStatus.Worktree, actual.Staging, actual.Worktree)
+				}
+			}
+		})
+	}
+}
+
+func TestBackendBranches(t *testing.T) {
+	origin := newTestRepository(t)
+	runGitCommand(t, origin, "branch", "pulled")
+	root := t.TempDir()
+	directory := filepath.Join(root, "clone")
+	runGitCommand(t, root, "clone", origin, directory)
+	runGitCommand(t, directory, "branch", "pulled", "origin/pulled")
+	runGitCommand(t, directory, "branch", "local")
+
+	writeFile(t, filepath.Join(directory, "modified.txt"), "not pushed")
+	runGitCommand(t, directory, "commit", "-am", "not pushed")
+
+	runGitCommand(t, origin, "checkout", "pulled")
+	writeFile(t, filepath.Join(origin, "modified.txt"), "not pulled")
+	runGitCommand(t, origin, "commit", "-am", "not pulled")
+	runGitCommand(t, directory, "fetch")
+
+	expected := []string{
+		"Local Only Branch local",
+		"Remote Ahead pulled",
+		"Remote Behind master",
+	}
+
+	repo, err := git.PlainOpen(directory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, backend := range backends {
+		t.Run(backend.ToString(), func(t *testing.T) {
+			checker := BranchChecker{
+				localOnlyBranch: true,
+				remoteAhead:     true,
+				remoteBehind:    true,
+				backend:         backend,
+			}
+			var verdicts []string
+			for response := range checker.Check(root, "clone", repo) {
+				switch verdict := response.Verdict.(type) {
+				case LocalOnlyBranch:
+					verdicts = append(verdicts, fmt.Sprint("Local Only Branch ", verdict.BranchName()))
+				case RemoteAhead:
+					verdicts = append(verdicts, fmt.Sprint("Remote Ahead ", verdict.LocalBranch()))
+				case RemoteBehind:
+					verdicts = append(verdicts, fmt.Sprint("Remote Behind ", verdict.LocalBranch()))
+				default:
+					t.Fatalf("unexpected response %v", response)
+				}
+			}
+			slices.Sort(verdicts)
+			if !slices.Equal(verdicts, expected) {
+				t.Errorf("expected verdicts %v, got %v", expected, verdicts)
+			}
+		})
+	}
+}
+
+func TestBackendGlobalExcludes(t *testing.T) {
+	configHome := t.TempDir()
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", configHome)
+	writeFile(t, filepath.Join(configHome, "git", "ignore"), ".idea/\n*.swp\n")
+
+	directory := newTestRepository(t)
+	writeFile(t, filepath.Join(directory, ".idea", "workspace.xml"), "ignored")
+	writeFile(t, filepath.Join(directory, "dir", ".file.txt.swp"), "ignored")
+	writeFile(t, filepath.Join(directory, "info-excluded.txt"), "ignored")
+	writeFile(t, filepath.Join(directory, ".git", "info", "exclude"), "info-excluded.txt\n")
+
+	repo, err := git.PlainOpen(directory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, backend := range backends {
+		t.Run(backend.ToString(), func(t *testing.T) {
+			status, err := backend.Status(directory, repo)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(status) != 0 {
+				t.Errorf("expected ignored files to be excluded, got %v", status)
+			}
+		})
+	}
+}
+
+func TestBackendConflicts(t *testing.T) {
+	directory := newTestRepository(t)
+	runGitCommand(t, directory, "checkout", "-b", "other")
+	writeFile(t, filepath.Join(directory, "modified.txt"), "other")
+	runGitCommand(t, directory, "commit", "-am", "other")
+	runGitCommand(t, directory, "checkout", "master")
+	writeFile(t, filepath.Join(directory, "modified.txt"), "master")
+	runGitCommand(t, directory, "commit", "-am", "master")
+	if gitCommand(directory, "merge", "other").Run() == nil {
+		t.Fatal("merge should fail with a conflict")
+	}
+
+	repo, err := git.PlainOpen(directory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, backend := range backends {
+		t.Run(backend.ToString(), func(t *testing.T) {
+			status, err := backend.Status(directory, repo)
+			if err != nil {
+				t.Fatal(err)
+			}
+			fileStatus, ok := status["modified.txt"]
+			if !ok || !isConflicted(fileStatus) {
+				t.Errorf("expected modified.txt to be conflicted, got %v", status)
+			}
+		})
+	}
+}
+
+func TestBackendFastForward(t *testing.T) {
+	for _, backend := range backends {
+		t.Run(backend.ToString(), func(t *testing.T) {
+			directory := newTestRepository(t)
+			runGitCommand(t, directory, "branch", "other")
+			writeFile(t, filepath.Join(directory, "modified.txt"), "next")
+			runGitCommand(t, directory, "commit", "-am", "next")
+			runGitCommand(t, directory, "checkout", "-b", "behind", "HEAD~1")
+
+			repo, err := git.PlainOpen(directory)
+			if err != nil {
+				t.Fatal(err)
+			}
+			master, err := repo.Reference(plumbing.NewBranchReferenceName("master"), false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			other, err := repo.Reference(plumbing.NewBranchReferenceName("other"), false)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = backend.FastForwardBranch(directory, repo, "other", master.Hash(), master.Hash())
+			if err == nil {
+				t.Error("branch should not be moved from an unexpected commit")
+			}
+			err = backend.FastForwardBranch(directory, repo, "other", other.Hash(), master.Hash())
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = backend.FastForwardHead(directory, repo, master.Hash())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, branch := range []string{"other", "behind"} {
+				ref, err := repo.Reference(plumbing.NewBranchReferenceName(branch), false)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if ref.Hash() != master.Hash() {
+					t.Errorf("branch %s should be fast-forwarded to %s, got %s", branch, master.Hash(), ref.Hash())
+				}
+			}
+			content, err := os.ReadFile(filepath.Join(directory, "modified.txt"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(content) != "next" {
+				t.Errorf("worktree should be updated, got %q", content)
+			}
+			status, err := backend.Status(directory, repo)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !status.IsClean() {
+				t.Errorf("worktree should be clean, got %v", status)
+			}
+		})
+	}
+}
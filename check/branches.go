@@ -1,11 +1,12 @@
 package check
 
 import (
-	"errors"
 	"fmt"
-	"io"
 	"iter"
+	"maps"
 	"path"
+	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/go-git/go-git/v5"
@@ -19,6 +20,9 @@ type BranchChecker struct {
 	localOnlyBranch bool
 	remoteAhead     bool
 	remoteBehind    bool
+	backend         Backend
+	// probe lists remote tips instead of reading remote-tracking branches, nil if not probing
+	probe *FetcherChecker
 }
 
 func NewBranchChecker(arguments arguments.Arguments) *BranchChecker {
@@ -29,6 +33,7 @@ func NewBranchChecker(arguments arguments.Arguments) *BranchChecker {
 		localOnlyBranch: arguments.LocalOnlyBranch,
 		remoteAhead:     arguments.RemoteAhead,
 		remoteBehind:    arguments.RemoteBehind,
+		backend:         NewBackend(arguments.Backend),
 	}
 }
 
@@ -37,7 +42,8 @@ func (b *BranchChecker) Check(
 	repo *git.Repository,
 ) iter.Seq[types.Response] {
 	return func(yield func(types.Response) bool) {
-		branches, err := repo.Branches()
+		fullPath := filepath.Join(directory, repository)
+		branchHashes, err := b.backend.LocalBranches(fullPath, repo)
 		if err != nil {
 			yield(
 				types.Response{Err: fmt.Errorf("cannot get branches for %s\n%s", repository, err)},
@@ -45,19 +51,7 @@ func (b *BranchChecker) Check(
 			return
 		}
 
-		var branchHashes = make(map[string]plumbing.Hash)
-		err = branches.ForEach(func(branch *plumbing.Reference) error {
-			branchHashes[branch.Name().Short()] = branch.Hash()
-			return nil
-		})
-		if err != nil {
-			yield(
-				types.Response{Err: fmt.Errorf("cannot get branches for %s\n%s", repository, err)},
-			)
-			return
-		}
-
-		references, err := repo.References()
+		references, err := b.backend.RemoteBranches(fullPath, repo)
 		if err != nil {
 			yield(
 				types.Response{
@@ -66,6 +60,20 @@ func (b *BranchChecker) Check(
 			)
 			return
 		}
+		var probed *ProbedRemotes
+		if b.probe != nil {
+			remotes, err := b.probe.ProbeRemotes(repo)
+			if err != nil {
+				yield(types.Response{Err: err})
+				return
+			}
+			// tips of probed remotes replace their remote-tracking branches, others are kept
+			references = slices.DeleteFunc(references, func(reference *plumbing.Reference) bool {
+				return remotes.IsProbed(reference.Name())
+			})
+			references = append(references, remotes.Branches...)
+			probed = &remotes
+		}
 		if !b.checkRemoteBranches(references, branchHashes, yield, directory, repository, repo) {
 			return
 		}
@@ -79,13 +87,53 @@ func (b *BranchChecker) Check(
 				}
 			}
 		}
+
+		if probed != nil && probed.AnyProbed() && b.remoteBehind {
+			if !b.checkTags(*probed, yield, directory, repository, repo) {
+				return
+			}
+		}
+
+		// remotes which could not be probed are reported after the verdicts of the others
+		if probed != nil {
+			for _, err := range probed.Errors {
+				if !yield(types.Response{Err: err}) {
+					return
+				}
+			}
+		}
 	}
 }
 
+// checkTags reports local tags which are not on any probed remote with the same hash,
+// it returns false when the consumer stopped the iteration
+func (b *BranchChecker) checkTags(
+	probed ProbedRemotes,
+	yield func(types.Response) bool,
+	directory, repository string,
+	repo *git.Repository,
+) bool {
+	tags, err := repo.Tags()
+	if err != nil {
+		return yield(types.Response{Err: fmt.Errorf("cannot get tags for %s\n%s", repository, err)})
+	}
+	stopped := false
+	_ = tags.ForEach(func(tag *plumbing.Reference) error {
+		if slices.Contains(probed.Tags[tag.Name()], tag.Hash()) {
+			return nil
+		}
+		if !yield(types.Response{Verdict: newUnpushedTag(directory, repository, tag)}) {
+			stopped = true
+			return storer.ErrStop
+		}
+		return nil
+	})
+	return !stopped
+}
+
 func newLocalOnlyBranch(directory, repository string, branch string) LocalOnlyBranch {
-	base := path.Base(directory)
 	return LocalOnlyBranch{
-		base:       base,
+		directory:  directory,
 		repository: repository,
 		branchName: branch,
 	}
@@ -96,32 +144,16 @@ func (b *BranchChecker) ToString() string {
 }
 
 func (b *BranchChecker) checkRemoteBranches(
-	references storer.ReferenceIter,
+	references []*plumbing.Reference,
 	branchHashes map[string]plumbing.Hash,
 	yield func(types.Response) bool,
 	directory, repository string,
 	repo *git.Repository,
 ) bool {
-	defer references.Close()
-	for {
-		ref, err := references.Next()
-		if err != nil {
-			if err == io.EOF {
-				break
-			}
-			yield(
-				types.Response{
-					Err: fmt.Errorf("%s, error while traversing references: %s", repository, err),
-				},
-			)
-			return false
-		}
-
-		if !ref.Name().IsRemote() {
-			continue
-		}
-
-		onlyBranchName, err := extractBranchName(repository, ref)
+	fullPath := filepath.Join(directory, repository)
+	remotes := remoteNames(repo)
+	for _, ref := range references {
+		remoteName, onlyBranchName, err := extractBranchName(repository, ref, remotes)
 		if err != nil {
 			yield(
 				types.Response{
@@ -136,39 +168,17 @@ func (b *BranchChecker) checkRemoteBranches(
 
 		remoteHash := ref.Hash()
 		if hasLocalClone && remoteHash != localHash {
-			remoteBranchCommit, err := repo.CommitObject(ref.Hash())
-			if err != nil {
-				yield(types.Response{
-					Err: fmt.Errorf(
-						"%s, error while getting branch \"%s\" remote commit: %s",
-						repository,
-						onlyBranchName,
-						err,
-					),
-				})
-				return false
+			// probed remote tips are unknown locally until fetched, so the remote is ahead
+			isRemoteAncestor := false
+			if repo.Storer.HasEncodedObject(remoteHash) == nil {
+				isRemoteAncestor, err = b.backend.IsAncestor(fullPath, repo, remoteHash, localHash)
 			}
-			localBranchCommit, err := repo.CommitObject(localHash)
 			if err != nil {
-				yield(types.Response{Err: fmt.Errorf(
-					"%s, error while getting branch \"%s\" local commit: %s",
-					repository,
-					onlyBranchName,
-					err,
-				)})
-				return false
-			}
-
-			isRemoteAncestor, err := remoteBranchCommit.IsAncestor(localBranchCommit)
-			if errors.Is(err, plumbing.ErrObjectNotFound) {
-				// partial git history, assuming ancestry connection not found
-				isRemoteAncestor = false
-			} else if err != nil {
 				yield(types.Response{Err: fmt.Errorf(
 					"%s: error while checking %s and %s ancestory: %s",
 					repository,
-					remoteBranchCommit.Hash,
-					localBranchCommit.Hash,
+					remoteHash,
+					localHash,
 					err,
 				)})
 				return false
@@ -178,7 +188,7 @@ func (b *BranchChecker) checkRemoteBranches(
 				if b.remoteBehind {
 					if !yield(
 						types.Response{
-							Verdict: newRemoteBehind(directory, repository, onlyBranchName, ref),
+							Verdict: newRemoteBehind(directory, repository, remoteName, onlyBranchName),
 						},
 					) {
 						return false
@@ -188,7 +198,7 @@ func (b *BranchChecker) checkRemoteBranches(
 				if b.remoteAhead {
 					if !yield(
 						types.Response{
-							Verdict: newRemoteAhead(directory, repository, onlyBranchName, ref),
+							Verdict: newRemoteAhead(directory, repository, remoteName, onlyBranchName),
 						},
 					) {
 						return false
@@ -200,39 +210,32 @@ func (b *BranchChecker) checkRemoteBranches(
 	return true
 }
 
-func newRemoteBehind(
-	directory, repository string,
-	onlyBranchName string,
-	ref *plumbing.Reference,
-) RemoteBehind {
-	base := path.Base(directory)
+// newRemoteBehind creates the verdict of a local branch ahead of the branch of the same name on the remote
+func newRemoteBehind(directory, repository string, remoteName, onlyBranchName string) RemoteBehind {
 	return RemoteBehind{
-		base:          base,
-		repository:    repository,
-		localBranch:   onlyBranchName,
-		remoteRefName: ref.Name().Short(),
+		directory:   directory,
+		repository:  repository,
+		localBranch: onlyBranchName,
+		remoteName:  remoteName,
 	}
 }
 
-func newRemoteAhead(
-	directory, repository string,
-	onlyBranchName string,
-	ref *plumbing.Reference,
-) RemoteAhead {
-	base := path.Base(directory)
+// newRemoteAhead creates the verdict of a branch of the remote ahead of the local branch of the same name
+func newRemoteAhead(directory, repository string, remoteName, onlyBranchName string) RemoteAhead {
 	return RemoteAhead{
-		base:          base,
-		repository:    repository,
-		localBranch:   onlyBranchName,
-		remoteRefName: ref.Name().Short(),
+		directory:   directory,
+		repository:  repository,
+		localBranch: onlyBranchName,
+		remoteName:  remoteName,
 	}
 }
 
 type RemoteBehind struct {
-	base          string
-	repository    string
-	localBranch   string
-	remoteRefName string
+	directory   string
+	repository  string
+	localBranch string
+	// remoteName is the remote whose branch of the same name is behind, it may contain "/"
+	remoteName string
 }
 
 func (u RemoteBehind) Repository() string {
@@ -240,22 +243,37 @@ func (u RemoteBehind) Repository() string {
 }
 
 func (u RemoteBehind) RepositoryPath() string {
-	return path.Join(u.base, u.repository)
+	return path.Join(path.Base(u.directory), u.repository)
+}
+
+func (u RemoteBehind) FullPath() string {
+	return filepath.Join(u.directory, u.repository)
 }
 
 func (u RemoteBehind) LocalBranch() string {
 	return u.localBranch
 }
 
+func (u RemoteBehind) RemoteName() string {
+	return u.remoteName
+}
+
+// RemoteBranch is the name of the branch on the remote
+func (u RemoteBehind) RemoteBranch() string {
+	return u.localBranch
+}
+
+// RemoteRefName is the short name of the remote-tracking branch, e.g. origin/main
 func (u RemoteBehind) RemoteRefName() string {
-	return u.remoteRefName
+	return u.remoteName + "/" + u.localBranch
 }
 
 type RemoteAhead struct {
-	base          string
-	repository    string
-	localBranch   string
-	remoteRefName string
+	directory   string
+	repository  string
+	localBranch string
+	// remoteName is the remote whose branch of the same name is ahead, it may contain "/"
+	remoteName string
 }
 
 func (u RemoteAhead) Repository() string {
@@ -263,19 +281,33 @@ func (u RemoteAhead) Repository() string {
 }
 
 func (u RemoteAhead) RepositoryPath() string {
-	return path.Join(u.base, u.repository)
+	return path.Join(path.Base(u.directory), u.repository)
+}
+
+func (u RemoteAhead) FullPath() string {
+	return filepath.Join(u.directory, u.repository)
 }
 
 func (u RemoteAhead) LocalBranch() string {
 	return u.localBranch
 }
 
+func (u RemoteAhead) RemoteName() string {
+	return u.remoteName
+}
+
+// RemoteBranch is the name of the branch on the remote
+func (u RemoteAhead) RemoteBranch() string {
+	return u.localBranch
+}
+
+// RemoteRefName is the short name of the remote-tracking branch, e.g. origin/main
 func (u RemoteAhead) RemoteRefName() string {
-	return u.remoteRefName
+	return u.remoteName + "/" + u.localBranch
 }
 
 type LocalOnlyBranch struct {
-	base       string
+	directory  string
 	repository string
 	branchName string
 }
@@ -285,21 +317,53 @@ func (u LocalOnlyBranch) Repository() string {
 }
 
 func (u LocalOnlyBranch) RepositoryPath() string {
-	return path.Join(u.base, u.repository)
+	return path.Join(path.Base(u.directory), u.repository)
+}
+
+func (u LocalOnlyBranch) FullPath() string {
+	return filepath.Join(u.directory, u.repository)
 }
 
 func (u LocalOnlyBranch) BranchName() string {
 	return u.branchName
 }
 
-func extractBranchName(repository string, ref *plumbing.Reference) (string, error) {
-	parts := strings.Split(ref.Name().Short(), "/")
-	if len(parts) < 2 {
-		return "", fmt.Errorf("unknown remote ref format \"%s\" in repository %s",
+// extractBranchName splits the remote-tracking branch into the remote and the branch name,
+// the longest configured remote name is taken as remote names may contain "/"
+func extractBranchName(repository string, ref *plumbing.Reference, remotes []string) (string, string, error) {
+	remoteName, onlyBranchName, found := splitRemoteRef(ref.Name(), remotes)
+	if !found {
+		// remote-tracking branches of removed remotes are split at the first "/"
+		remoteName, onlyBranchName, found = strings.Cut(strings.TrimPrefix(ref.Name().String(), "refs/remotes/"), "/")
+	}
+	if !found || remoteName == "" || onlyBranchName == "" {
+		return "", "", fmt.Errorf("unknown remote ref format \"%s\" in repository %s",
 			ref.Name().Short(),
 			repository,
 		)
 	}
-	onlyBranchName := strings.Join(parts[1:], "/")
-	return onlyBranchName, nil
+	return remoteName, onlyBranchName, nil
+}
+
+// splitRemoteRef splits refs/remotes/<remote>/<branch> by the longest of remotes matching it
+func splitRemoteRef(reference plumbing.ReferenceName, remotes []string) (string, string, bool) {
+	remoteName := ""
+	for _, name := range remotes {
+		if strings.HasPrefix(reference.String(), "refs/remotes/"+name+"/") && len(name) > len(remoteName) {
+			remoteName = name
+		}
+	}
+	if remoteName == "" {
+		return "", "", false
+	}
+	return remoteName, strings.TrimPrefix(reference.String(), "refs/remotes/"+remoteName+"/"), true
+}
+
+// remoteNames returns names of configured remotes
+func remoteNames(repo *git.Repository) []string {
+	config, err := repo.Config()
+	if err != nil {
+		return nil
+	}
+	return slices.Collect(maps.Keys(config.Remotes))
 }
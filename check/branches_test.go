@@ -0,0 +1,30 @@
+package check
+
+import (
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+func TestExtractBranchName(t *testing.T) {
+	remotes := []string{"origin", "team", "team/backup"}
+	tests := []struct {
+		reference    string
+		remoteName   string
+		remoteBranch string
+	}{
+		{"refs/remotes/origin/main", "origin", "main"},
+		{"refs/remotes/origin/feature/x", "origin", "feature/x"},
+		{"refs/remotes/team/backup/main", "team/backup", "main"},
+		{"refs/remotes/team/feature/x", "team", "feature/x"},
+		{"refs/remotes/removed/main", "removed", "main"},
+	}
+	for _, test := range tests {
+		ref := plumbing.NewHashReference(plumbing.ReferenceName(test.reference), plumbing.ZeroHash)
+		remoteName, remoteBranch, err := extractBranchName("repo", ref, remotes)
+		if err != nil || remoteName != test.remoteName || remoteBranch != test.remoteBranch {
+			t.Errorf(`%s should be split into %s and %s, got %s and %s, %v`,
+				test.reference, test.remoteName, test.remoteBranch, remoteName, remoteBranch, err)
+		}
+	}
+}
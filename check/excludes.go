@@ -0,0 +1,85 @@
+package check
+
+import (
+	"bufio"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+)
+
+// ExcludesFile returns the path of core.excludesFile looking in repository, global and system
+// configurations, it defaults to $XDG_CONFIG_HOME/git/ignore as in git
+func ExcludesFile(repo *git.Repository) string {
+	configs := make([]*config.Config, 0, 3)
+	local, err := repo.Config()
+	if err == nil {
+		configs = append(configs, local)
+	}
+	for _, scope := range []config.Scope{config.GlobalScope, config.SystemScope} {
+		scoped, err := config.LoadConfig(scope)
+		if err == nil {
+			configs = append(configs, scoped)
+		}
+	}
+	for _, cfg := range configs {
+		excludesFile := cfg.Raw.Section("core").Option("excludesfile")
+		if excludesFile != "" {
+			return expandHome(excludesFile)
+		}
+	}
+
+	configHome := os.Getenv("XDG_CONFIG_HOME")
+	if configHome == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		configHome = filepath.Join(home, ".config")
+	}
+	return filepath.Join(configHome, "git", "ignore")
+}
+
+func expandHome(path string) string {
+	rest, found := strings.CutPrefix(path, "~/")
+	if !found {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, rest)
+}
+
+// globalExcludes reads patterns of core.excludesFile, which go-git's Worktree.Status ignores.
+// Patterns of .git/info/exclude and .gitignore files are read by go-git itself.
+func globalExcludes(repo *git.Repository) ([]gitignore.Pattern, error) {
+	excludesFile := ExcludesFile(repo)
+	if excludesFile == "" {
+		return nil, nil
+	}
+	file, err := os.Open(excludesFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var patterns []gitignore.Pattern
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "#") && strings.TrimSpace(line) != "" {
+			patterns = append(patterns, gitignore.ParsePattern(line, nil))
+		}
+	}
+	return patterns, scanner.Err()
+}
@@ -1,30 +1,122 @@
 package check
 
 import (
+	"fmt"
 	"regexp"
+	"time"
 
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/transport"
 	"github.com/gobwas/glob"
 	"github.com/hov1417/assayer/arguments"
+	"github.com/hov1417/assayer/auth"
+	"github.com/hov1417/assayer/cache"
 )
 
 type FetcherChecker struct {
-	FetchType  arguments.FetchType
-	FetchGroup *glob.Glob
+	FetchType arguments.FetchType
+	// selectors of FetchSome, a remote is fetched when all given selectors match
+	FetchGroup  *glob.Glob
+	FetchHost   *glob.Glob
+	FetchPath   *glob.Glob
+	FetchRemote *glob.Glob
+	// Probe lists tips of selected remotes instead of fetching them
+	Probe     bool
+	Prune     bool
+	Tags      arguments.FetchTags
+	Auth      *auth.Provider
+	Scheduler *FetchScheduler
+	// MaxAge skips remotes fetched more recently, zero fetches always
+	MaxAge time.Duration
 }
 
-var re = regexp.MustCompile(`[/:]`)
-
-func (f *FetcherChecker) NeedsFetch(remoteUrl string) bool {
+func (f *FetcherChecker) NeedsFetch(remoteName, remoteUrl string) bool {
 	if f.FetchType == arguments.FetchAll {
 		return true
 	}
 	if f.FetchType == arguments.FetchNone {
 		return false
 	}
-	res := re.Split(remoteUrl, -1)
-	if len(res) < 2 {
+	if f.FetchRemote != nil && !(*f.FetchRemote).Match(remoteName) {
+		return false
+	}
+	parsedUrl, err := ParseRemoteUrl(remoteUrl)
+	if err != nil {
+		// urls which are not valid endpoints are still matched by group as the segment before the name
+		return f.FetchGroup != nil && f.FetchHost == nil && f.FetchPath == nil &&
+			(*f.FetchGroup).Match(splitGroup(remoteUrl))
+	}
+	if f.FetchGroup != nil && !(*f.FetchGroup).Match(parsedUrl.Group()) {
+		return false
+	}
+	if f.FetchHost != nil && !(*f.FetchHost).Match(parsedUrl.Host) {
+		return false
+	}
+	if f.FetchPath != nil && !(*f.FetchPath).Match(parsedUrl.Path) {
 		return false
 	}
-	group := res[len(res)-2]
-	return (*f.FetchGroup).Match(group)
+	return true
+}
+
+var urlSeparators = regexp.MustCompile(`[/:]`)
+
+// splitGroup returns the segment before the repository name as split before urls were parsed
+func splitGroup(remoteUrl string) string {
+	segments := urlSeparators.Split(remoteUrl, -1)
+	if len(segments) < 2 {
+		return ""
+	}
+	return segments[len(segments)-2]
+}
+
+// AuthFor returns credentials for fetching from remoteUrl, nil if anonymous
+func (f *FetcherChecker) AuthFor(remoteUrl string) (transport.AuthMethod, error) {
+	if f.Auth == nil {
+		return nil, nil
+	}
+	return f.Auth.AuthFor(remoteUrl)
+}
+
+// IsFresh reports whether the remote was fetched within MaxAge
+func (f *FetcherChecker) IsFresh(repositoryPath, remoteName string) bool {
+	if f.MaxAge <= 0 {
+		return false
+	}
+	return time.Since(cache.LastFetch(repositoryPath, remoteName)) < f.MaxAge
+}
+
+// Fetch fetches the remote through the scheduler and records the fetch time
+func (f *FetcherChecker) Fetch(
+	repo *git.Repository,
+	repositoryPath, remoteName, remoteUrl string,
+) error {
+	fetchAuth, err := f.AuthFor(remoteUrl)
+	if err != nil {
+		return fmt.Errorf("error getting credentials for remote %s\n%s", remoteName, err)
+	}
+	err = f.Scheduler.Fetch(repo, &git.FetchOptions{
+		RemoteName: remoteName,
+		Auth:       fetchAuth,
+		Prune:      f.Prune,
+		Tags:       f.tagMode(),
+	}, remoteUrl)
+	if err != nil {
+		return fmt.Errorf("error fetching remote %s\n%s", remoteName, err)
+	}
+	err = cache.RecordFetch(repositoryPath, remoteName, time.Now())
+	if err != nil {
+		return fmt.Errorf("error recording fetch of remote %s\n%s", remoteName, err)
+	}
+	return nil
+}
+
+func (f *FetcherChecker) tagMode() git.TagMode {
+	switch f.Tags {
+	case arguments.FetchTagsAll:
+		return git.AllTags
+	case arguments.FetchTagsNone:
+		return git.NoTags
+	default:
+		return git.TagFollowing
+	}
 }
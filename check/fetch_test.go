@@ -7,25 +7,74 @@ import (
 	"github.com/hov1417/assayer/arguments"
 )
 
+var remoteUrls = []string{
+	"git@github.com:go-git/go-git.git",
+	"https://github.com/go-git/go-git.git",
+	"https://codeberg.org/ziglang/zig.git",
+	"git@github.com:hov1417/assayer.git",
+	"ssh://login@server.com:12345/group/repository.git",
+	"https://gitlab.com/group/subgroup/project.git",
+	"https://dev.azure.com/organization/project/_git/repository",
+	"file:///srv/git/mirrors/linux.git",
+}
+
+func compileGlob(t *testing.T, pattern string) *glob.Glob {
+	t.Helper()
+	compiled, err := glob.Compile(pattern, '/')
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &compiled
+}
+
+func TestParseRemoteUrl(t *testing.T) {
+	tests := []struct {
+		url      string
+		expected RemoteUrl
+	}{
+		{"git@github.com:go-git/go-git.git", RemoteUrl{"github.com", "go-git", "go-git"}},
+		{"https://github.com/go-git/go-git.git", RemoteUrl{"github.com", "go-git", "go-git"}},
+		{"https://github.com/go-git/go-git/", RemoteUrl{"github.com", "go-git", "go-git"}},
+		{"ssh://login@server.com:12345/group/repository.git", RemoteUrl{"server.com", "group", "repository"}},
+		{"https://gitlab.example.com:8443/group/subgroup/project.git", RemoteUrl{"gitlab.example.com", "group/subgroup", "project"}},
+		{"git@gitlab.com:group/subgroup/deeper/project.git", RemoteUrl{"gitlab.com", "group/subgroup/deeper", "project"}},
+		{"git://git.kernel.org/pub/scm/git/git.git", RemoteUrl{"git.kernel.org", "pub/scm/git", "git"}},
+		{"https://dev.azure.com/organization/project/_git/repository", RemoteUrl{"dev.azure.com", "organization/project", "repository"}},
+		{"git@ssh.dev.azure.com:v3/organization/project/repository", RemoteUrl{"ssh.dev.azure.com", "organization/project", "repository"}},
+		{"file:///srv/git/mirrors/linux.git", RemoteUrl{"", "srv/git/mirrors", "linux"}},
+		{"/srv/git/repository", RemoteUrl{"", "srv/git", "repository"}},
+	}
+	for _, test := range tests {
+		actual, err := ParseRemoteUrl(test.url)
+		if err != nil {
+			t.Errorf("%s: unexpected error %s", test.url, err)
+			continue
+		}
+		if actual != test.expected {
+			t.Errorf("%s: expected %+v, got %+v", test.url, test.expected, actual)
+		}
+	}
+}
+
 func TestFetcherAll(t *testing.T) {
 	fetcher := FetcherChecker{
 		FetchType:  arguments.FetchAll,
 		FetchGroup: nil,
 	}
 
-	if !fetcher.NeedsFetch("git@github.com:go-git/go-git.git") {
+	if !fetcher.NeedsFetch("origin", "git@github.com:go-git/go-git.git") {
 		t.Errorf(`Should return true when type is all`)
 	}
-	if !fetcher.NeedsFetch("https://github.com/go-git/go-git.git") {
+	if !fetcher.NeedsFetch("origin", "https://github.com/go-git/go-git.git") {
 		t.Errorf(`Should return true when type is all`)
 	}
-	if !fetcher.NeedsFetch("https://codeberg.org/ziglang/zig.git") {
+	if !fetcher.NeedsFetch("origin", "https://codeberg.org/ziglang/zig.git") {
 		t.Errorf(`Should return true when type is all`)
 	}
-	if !fetcher.NeedsFetch("git@github.com:hov1417/assayer.git") {
+	if !fetcher.NeedsFetch("origin", "git@github.com:hov1417/assayer.git") {
 		t.Errorf(`Should return true when type is all`)
 	}
-	if !fetcher.NeedsFetch("git clone ssh://login@server.com:12345/group/repository.git") {
+	if !fetcher.NeedsFetch("origin", "git clone ssh://login@server.com:12345/group/repository.git") {
 		t.Errorf(`Should return true when type is all`)
 	}
 }
@@ -36,19 +85,19 @@ func TestFetcherNone(t *testing.T) {
 		FetchGroup: nil,
 	}
 
-	if fetcher.NeedsFetch("git@github.com:go-git/go-git.git") {
+	if fetcher.NeedsFetch("origin", "git@github.com:go-git/go-git.git") {
 		t.Errorf(`Should return false when type is none`)
 	}
-	if fetcher.NeedsFetch("https://github.com/go-git/go-git.git") {
+	if fetcher.NeedsFetch("origin", "https://github.com/go-git/go-git.git") {
 		t.Errorf(`Should return false when type is none`)
 	}
-	if fetcher.NeedsFetch("https://codeberg.org/ziglang/zig.git") {
+	if fetcher.NeedsFetch("origin", "https://codeberg.org/ziglang/zig.git") {
 		t.Errorf(`Should return false when type is none`)
 	}
-	if fetcher.NeedsFetch("git@github.com:hov1417/assayer.git") {
+	if fetcher.NeedsFetch("origin", "git@github.com:hov1417/assayer.git") {
 		t.Errorf(`Should return false when type is none`)
 	}
-	if fetcher.NeedsFetch("git clone ssh://login@server.com:12345/group/repository.git") {
+	if fetcher.NeedsFetch("origin", "git clone ssh://login@server.com:12345/group/repository.git") {
 		t.Errorf(`Should return false when type is none`)
 	}
 }
@@ -64,19 +113,19 @@ func TestFetcherGroup(t *testing.T) {
 		FetchGroup: &group,
 	}
 
-	if !fetcher.NeedsFetch("git@github.com:go-git/go-git.git") {
+	if !fetcher.NeedsFetch("origin", "git@github.com:go-git/go-git.git") {
 		t.Errorf(`Should return true when type is some but glob is all`)
 	}
-	if !fetcher.NeedsFetch("https://github.com/go-git/go-git.git") {
+	if !fetcher.NeedsFetch("origin", "https://github.com/go-git/go-git.git") {
 		t.Errorf(`Should return true when type is some but glob is all`)
 	}
-	if !fetcher.NeedsFetch("https://codeberg.org/ziglang/zig.git") {
+	if !fetcher.NeedsFetch("origin", "https://codeberg.org/ziglang/zig.git") {
 		t.Errorf(`Should return true when type is some but glob is all`)
 	}
-	if !fetcher.NeedsFetch("git@github.com:hov1417/assayer.git") {
+	if !fetcher.NeedsFetch("origin", "git@github.com:hov1417/assayer.git") {
 		t.Errorf(`Should return true when type is some but glob is all`)
 	}
-	if !fetcher.NeedsFetch("git clone ssh://login@server.com:12345/group/repository.git") {
+	if !fetcher.NeedsFetch("origin", "git clone ssh://login@server.com:12345/group/repository.git") {
 		t.Errorf(`Should return true when type is some but glob is all`)
 	}
 }
@@ -92,19 +141,19 @@ func TestFetcherGroupSingleGroup(t *testing.T) {
 		FetchGroup: &group,
 	}
 
-	if !fetcher.NeedsFetch("git@github.com:go-git/go-git.git") {
+	if !fetcher.NeedsFetch("origin", "git@github.com:go-git/go-git.git") {
 		t.Errorf(`Should return true when glob is go-git`)
 	}
-	if !fetcher.NeedsFetch("https://github.com/go-git/go-git.git") {
+	if !fetcher.NeedsFetch("origin", "https://github.com/go-git/go-git.git") {
 		t.Errorf(`Should return true when glob is go-git`)
 	}
-	if fetcher.NeedsFetch("https://codeberg.org/ziglang/zig.git") {
+	if fetcher.NeedsFetch("origin", "https://codeberg.org/ziglang/zig.git") {
 		t.Errorf(`Should return false when glob is go-git`)
 	}
-	if fetcher.NeedsFetch("git@github.com:hov1417/assayer.git") {
+	if fetcher.NeedsFetch("origin", "git@github.com:hov1417/assayer.git") {
 		t.Errorf(`Should return false when glob is go-git`)
 	}
-	if fetcher.NeedsFetch("git clone ssh://login@server.com:12345/group/repository.git") {
+	if fetcher.NeedsFetch("origin", "git clone ssh://login@server.com:12345/group/repository.git") {
 		t.Errorf(`Should return false when glob is go-git`)
 	}
 }
@@ -120,19 +169,92 @@ func TestFetcherGroupMultipleGroups(t *testing.T) {
 		FetchGroup: &group,
 	}
 
-	if !fetcher.NeedsFetch("git@github.com:go-git/go-git.git") {
+	if !fetcher.NeedsFetch("origin", "git@github.com:go-git/go-git.git") {
 		t.Errorf(`Should return true`)
 	}
-	if !fetcher.NeedsFetch("https://github.com/go-git/go-git.git") {
+	if !fetcher.NeedsFetch("origin", "https://github.com/go-git/go-git.git") {
 		t.Errorf(`Should return true`)
 	}
-	if !fetcher.NeedsFetch("https://codeberg.org/ziglang/zig.git") {
+	if !fetcher.NeedsFetch("origin", "https://codeberg.org/ziglang/zig.git") {
 		t.Errorf(`Should return true`)
 	}
-	if fetcher.NeedsFetch("git@github.com:hov1417/assayer.git") {
+	if fetcher.NeedsFetch("origin", "git@github.com:hov1417/assayer.git") {
 		t.Errorf(`Should return false`)
 	}
-	if !fetcher.NeedsFetch("git clone ssh://login@server.com:12345/group/repository.git") {
+	if !fetcher.NeedsFetch("origin", "git clone ssh://login@server.com:12345/group/repository.git") {
 		t.Errorf(`Should return true`)
 	}
 }
+
+func TestFetcherSelectors(t *testing.T) {
+	tests := []struct {
+		name     string
+		fetcher  FetcherChecker
+		remote   string
+		expected []bool
+	}{
+		{
+			name:     "innermost group",
+			fetcher:  FetcherChecker{FetchType: arguments.FetchSome, FetchGroup: compileGlob(t, "subgroup")},
+			remote:   "origin",
+			expected: []bool{false, false, false, false, false, true, false, false},
+		},
+		{
+			name:     "host",
+			fetcher:  FetcherChecker{FetchType: arguments.FetchSome, FetchHost: compileGlob(t, "github.com")},
+			remote:   "origin",
+			expected: []bool{true, true, false, true, false, false, false, false},
+		},
+		{
+			name:     "host wildcard",
+			fetcher:  FetcherChecker{FetchType: arguments.FetchSome, FetchHost: compileGlob(t, "*.{org,com}")},
+			remote:   "origin",
+			expected: []bool{true, true, true, true, true, true, true, false},
+		},
+		{
+			name:     "full path",
+			fetcher:  FetcherChecker{FetchType: arguments.FetchSome, FetchPath: compileGlob(t, "group/**")},
+			remote:   "origin",
+			expected: []bool{false, false, false, false, false, true, false, false},
+		},
+		{
+			name:     "top level path",
+			fetcher:  FetcherChecker{FetchType: arguments.FetchSome, FetchPath: compileGlob(t, "group*")},
+			remote:   "origin",
+			expected: []bool{false, false, false, false, true, false, false, false},
+		},
+		{
+			name:     "remote name",
+			fetcher:  FetcherChecker{FetchType: arguments.FetchSome, FetchRemote: compileGlob(t, "upstream")},
+			remote:   "origin",
+			expected: []bool{false, false, false, false, false, false, false, false},
+		},
+		{
+			name: "host and remote name",
+			fetcher: FetcherChecker{
+				FetchType:   arguments.FetchSome,
+				FetchHost:   compileGlob(t, "github.com"),
+				FetchRemote: compileGlob(t, "{origin,upstream}"),
+			},
+			remote:   "upstream",
+			expected: []bool{true, true, false, true, false, false, false, false},
+		},
+		{
+			name: "host and group",
+			fetcher: FetcherChecker{
+				FetchType:  arguments.FetchSome,
+				FetchHost:  compileGlob(t, "github.com"),
+				FetchGroup: compileGlob(t, "hov1417"),
+			},
+			remote:   "origin",
+			expected: []bool{false, false, false, true, false, false, false, false},
+		},
+	}
+	for _, test := range tests {
+		for i, remoteUrl := range remoteUrls {
+			if test.fetcher.NeedsFetch(test.remote, remoteUrl) != test.expected[i] {
+				t.Errorf("%s: expected %t for %s of %s", test.name, test.expected[i], test.remote, remoteUrl)
+			}
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package check
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/hov1417/assayer/types"
+)
+
+// returns value indicating to "continue" or not
+func (w *WorkTreeChecker) checkFiles(
+	directory, repository string,
+	status git.Status,
+	repo *git.Repository,
+	yield func(types.Response) bool,
+) bool {
+	var changedItems []string
+	var untrackedItems []string
+	for itemPath, s := range status {
+		if s.Worktree == git.Untracked {
+			untrackedItems = append(untrackedItems, itemPath)
+		} else if s.Staging != git.Unmodified || s.Worktree != git.Unmodified {
+			changedItems = append(changedItems, itemPath)
+		}
+	}
+
+	var verdicts []types.Verdict
+	slices.Sort(changedItems)
+	for _, item := range changedItems {
+		verdicts = append(
+			verdicts,
+			w.fileVerdicts(directory, repository, item, status[item])...,
+		)
+	}
+	if w.untracked {
+		collapsed, err := collapseUntracked(directory, repository, untrackedItems, repo)
+		if err != nil {
+			yield(types.Response{Err: err})
+			return false
+		}
+		for _, item := range collapsed {
+			verdicts = append(verdicts, newUntracked(directory, repository, item))
+		}
+	}
+
+	for i, verdict := range verdicts {
+		if w.filesLimit > 0 && i == w.filesLimit {
+			return yield(types.Response{
+				Verdict: newMoreFiles(directory, repository, len(verdicts)-w.filesLimit),
+			})
+		}
+		if !yield(types.Response{Verdict: verdict}) {
+			return false
+		}
+	}
+	return true
+}
+
+// collapseUntracked replaces untracked files with their topmost directory
+// that contains no tracked files, as `git status` does
+func collapseUntracked(
+	directory, repository string,
+	untrackedItems []string,
+	repo *git.Repository,
+) ([]string, error) {
+	index, err := repo.Storer.Index()
+	if err != nil {
+		return nil, err
+	}
+	trackedDirectories := make(map[string]bool)
+	for _, entry := range index.Entries {
+		for dir := path.Dir(entry.Name); dir != "."; dir = path.Dir(dir) {
+			trackedDirectories[dir] = true
+		}
+	}
+
+	fullRepository := filepath.Join(directory, repository)
+	collapsed := make(map[string]bool)
+	for _, item := range untrackedItems {
+		parts := strings.Split(item, "/")
+		collapsedItem := item
+		for i := 1; i < len(parts); i++ {
+			dir := strings.Join(parts[:i], "/")
+			if !trackedDirectories[dir] {
+				collapsedItem = dir + "/"
+				break
+			}
+		}
+		if collapsedItem == item {
+			// the git backend reports untracked directories already collapsed
+			info, err := os.Stat(filepath.Join(fullRepository, item))
+			if err == nil && info.IsDir() {
+				collapsedItem = item + "/"
+			}
+		}
+		collapsed[collapsedItem] = true
+	}
+
+	result := make([]string, 0, len(collapsed))
+	for item := range collapsed {
+		result = append(result, item)
+	}
+	slices.Sort(result)
+	return result, nil
+}
+
+// MoreFiles is the number of changed files left out of the report because of the files limit
+type MoreFiles struct {
+	directory  string
+	repository string
+	count      int
+}
+
+func newMoreFiles(directory, repository string, count int) MoreFiles {
+	return MoreFiles{
+		directory:  directory,
+		repository: repository,
+		count:      count,
+	}
+}
+
+func (u MoreFiles) Repository() string {
+	return u.repository
+}
+
+func (u MoreFiles) RepositoryPath() string {
+	return path.Join(path.Base(u.directory), u.repository)
+}
+
+func (u MoreFiles) FullPath() string {
+	return filepath.Join(u.directory, u.repository)
+}
+
+func (u MoreFiles) Count() int {
+	return u.count
+}
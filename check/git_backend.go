@@ -0,0 +1,210 @@
+package check
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// GitBackend reads repositories with the system git binary,
+// which respects global excludes, core.untrackedCache and is faster on large worktrees
+type GitBackend struct {
+}
+
+// RunGit runs git in the repository at path and returns its standard output
+func RunGit(path string, args ...string) ([]byte, error) {
+	return RunGitWithEnv(path, nil, args...)
+}
+
+// RunGitWithEnv runs git as RunGit does, with additional environment variables
+func RunGitWithEnv(path string, env []string, args ...string) ([]byte, error) {
+	command := exec.Command("git", append([]string{"-C", path}, args...)...)
+	// status should not refresh the index, it would invalidate cached fingerprints
+	command.Env = append(append(os.Environ(), "GIT_OPTIONAL_LOCKS=0"), env...)
+	var stderr bytes.Buffer
+	command.Stderr = &stderr
+	output, err := command.Output()
+	if err != nil {
+		return nil, fmt.Errorf(
+			"git %s failed: %s\n%s",
+			strings.Join(args, " "),
+			err,
+			strings.TrimSpace(stderr.String()),
+		)
+	}
+	return output, nil
+}
+
+func (g *GitBackend) Status(path string, _ *git.Repository) (git.Status, error) {
+	output, err := RunGit(path, "status", "--porcelain=v2", "-z", "--branch")
+	if err != nil {
+		return nil, err
+	}
+	return parsePorcelainStatus(output)
+}
+
+// parsePorcelainStatus converts `git status --porcelain=v2 -z` output to go-git's status
+func parsePorcelainStatus(output []byte) (git.Status, error) {
+	status := make(git.Status)
+	records := strings.Split(string(output), "\x00")
+	for i := 0; i < len(records); i++ {
+		record := records[i]
+		if record == "" {
+			continue
+		}
+		switch record[0] {
+		case '#', '!':
+			continue
+		case '?':
+			// untracked directories are reported collapsed, with a trailing slash
+			itemPath := strings.TrimSuffix(record[2:], "/")
+			status[itemPath] = &git.FileStatus{Staging: git.Untracked, Worktree: git.Untracked}
+		case '1', '2', 'u':
+			// 1 XY sub mH mI mW hH hI path
+			// 2 XY sub mH mI mW hH hI Xscore path, followed by original path record
+			// u XY sub m1 m2 m3 mW h1 h2 h3 path
+			fieldCount := map[byte]int{'1': 9, '2': 10, 'u': 11}[record[0]]
+			fields := strings.SplitN(record, " ", fieldCount)
+			if len(fields) != fieldCount {
+				return nil, fmt.Errorf("unknown status record \"%s\"", record)
+			}
+			fileStatus := &git.FileStatus{
+				Staging:  porcelainStatusCode(fields[1][0]),
+				Worktree: porcelainStatusCode(fields[1][1]),
+			}
+			if record[0] == 'u' {
+				fileStatus.Staging = git.UpdatedButUnmerged
+				fileStatus.Worktree = git.UpdatedButUnmerged
+			}
+			if record[0] == '2' {
+				i++
+				if i < len(records) {
+					fileStatus.Extra = records[i]
+				}
+			}
+			status[fields[fieldCount-1]] = fileStatus
+		default:
+			return nil, fmt.Errorf("unknown status record \"%s\"", record)
+		}
+	}
+	return status, nil
+}
+
+func porcelainStatusCode(code byte) git.StatusCode {
+	switch code {
+	case '.':
+		return git.Unmodified
+	case 'M', 'T':
+		return git.Modified
+	case 'A':
+		return git.Added
+	case 'D':
+		return git.Deleted
+	case 'R':
+		return git.Renamed
+	case 'C':
+		return git.Copied
+	case 'U':
+		return git.UpdatedButUnmerged
+	default:
+		return git.StatusCode(code)
+	}
+}
+
+func (g *GitBackend) forEachRef(path string, patterns ...string) ([]*plumbing.Reference, error) {
+	args := append([]string{"for-each-ref", "--format=%(objectname) %(refname)"}, patterns...)
+	output, err := RunGit(path, args...)
+	if err != nil {
+		return nil, err
+	}
+	var references []*plumbing.Reference
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		if line == "" {
+			continue
+		}
+		hash, name, found := strings.Cut(line, " ")
+		if !found {
+			return nil, fmt.Errorf("unknown for-each-ref output \"%s\"", line)
+		}
+		references = append(
+			references,
+			plumbing.NewHashReference(plumbing.ReferenceName(name), plumbing.NewHash(hash)),
+		)
+	}
+	return references, nil
+}
+
+func (g *GitBackend) LocalBranches(path string, _ *git.Repository) (map[string]plumbing.Hash, error) {
+	references, err := g.forEachRef(path, "refs/heads")
+	if err != nil {
+		return nil, err
+	}
+	var branchHashes = make(map[string]plumbing.Hash)
+	for _, ref := range references {
+		branchHashes[ref.Name().Short()] = ref.Hash()
+	}
+	return branchHashes, nil
+}
+
+func (g *GitBackend) RemoteBranches(path string, _ *git.Repository) ([]*plumbing.Reference, error) {
+	return g.forEachRef(path, "refs/remotes")
+}
+
+func (g *GitBackend) IsAncestor(
+	path string,
+	_ *git.Repository,
+	ancestor, descendant plumbing.Hash,
+) (bool, error) {
+	output, err := RunGit(
+		path,
+		"rev-list",
+		"--left-right",
+		"--count",
+		ancestor.String()+"..."+descendant.String(),
+	)
+	if err != nil {
+		return false, err
+	}
+	counts := strings.Fields(string(output))
+	if len(counts) != 2 {
+		return false, fmt.Errorf("unknown rev-list output \"%s\"", output)
+	}
+	ancestorOnly, err := strconv.Atoi(counts[0])
+	if err != nil {
+		return false, err
+	}
+	return ancestorOnly == 0, nil
+}
+
+func (g *GitBackend) FastForwardBranch(
+	path string,
+	_ *git.Repository,
+	branch string,
+	from, to plumbing.Hash,
+) error {
+	_, err := RunGit(
+		path,
+		"update-ref",
+		"-m",
+		"assayer: fast-forward",
+		plumbing.NewBranchReferenceName(branch).String(),
+		to.String(),
+		from.String(),
+	)
+	return err
+}
+
+func (g *GitBackend) FastForwardHead(path string, _ *git.Repository, to plumbing.Hash) error {
+	_, err := RunGit(path, "merge", "--ff-only", "--quiet", to.String())
+	return err
+}
+
+func (g *GitBackend) ToString() string {
+	return "git"
+}
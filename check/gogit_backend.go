@@ -0,0 +1,133 @@
+package check
+
+import (
+	"errors"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// GoGitBackend reads repositories with go-git
+type GoGitBackend struct {
+}
+
+func (g *GoGitBackend) Status(_ string, repo *git.Repository) (git.Status, error) {
+	tree, err := repo.Worktree()
+	if err != nil {
+		return nil, err
+	}
+	patterns, err := globalExcludes(repo)
+	if err != nil {
+		return nil, err
+	}
+	tree.Excludes = append(tree.Excludes, patterns...)
+	status, err := tree.Status()
+	if err != nil {
+		return nil, err
+	}
+
+	// go-git does not report unmerged files, they are index entries with non-zero stages
+	index, err := repo.Storer.Index()
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range index.Entries {
+		if entry.Stage != 0 {
+			status[entry.Name] = &git.FileStatus{
+				Staging:  git.UpdatedButUnmerged,
+				Worktree: git.UpdatedButUnmerged,
+			}
+		}
+	}
+	return status, nil
+}
+
+func (g *GoGitBackend) LocalBranches(
+	_ string,
+	repo *git.Repository,
+) (map[string]plumbing.Hash, error) {
+	branches, err := repo.Branches()
+	if err != nil {
+		return nil, err
+	}
+
+	var branchHashes = make(map[string]plumbing.Hash)
+	err = branches.ForEach(func(branch *plumbing.Reference) error {
+		branchHashes[branch.Name().Short()] = branch.Hash()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return branchHashes, nil
+}
+
+func (g *GoGitBackend) RemoteBranches(
+	_ string,
+	repo *git.Repository,
+) ([]*plumbing.Reference, error) {
+	references, err := repo.References()
+	if err != nil {
+		return nil, err
+	}
+
+	var remoteBranches []*plumbing.Reference
+	err = references.ForEach(func(ref *plumbing.Reference) error {
+		if ref.Name().IsRemote() {
+			remoteBranches = append(remoteBranches, ref)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return remoteBranches, nil
+}
+
+func (g *GoGitBackend) IsAncestor(
+	_ string,
+	repo *git.Repository,
+	ancestor, descendant plumbing.Hash,
+) (bool, error) {
+	ancestorCommit, err := repo.CommitObject(ancestor)
+	if err != nil {
+		return false, err
+	}
+	descendantCommit, err := repo.CommitObject(descendant)
+	if err != nil {
+		return false, err
+	}
+
+	isAncestor, err := ancestorCommit.IsAncestor(descendantCommit)
+	if errors.Is(err, plumbing.ErrObjectNotFound) {
+		// partial git history, assuming ancestry connection not found
+		return false, nil
+	}
+	return isAncestor, err
+}
+
+func (g *GoGitBackend) FastForwardBranch(
+	_ string,
+	repo *git.Repository,
+	branch string,
+	from, to plumbing.Hash,
+) error {
+	name := plumbing.NewBranchReferenceName(branch)
+	return repo.Storer.CheckAndSetReference(
+		plumbing.NewHashReference(name, to),
+		plumbing.NewHashReference(name, from),
+	)
+}
+
+func (g *GoGitBackend) FastForwardHead(_ string, repo *git.Repository, to plumbing.Hash) error {
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return err
+	}
+	// the worktree is clean, so hard reset only applies changes between the commits
+	return worktree.Reset(&git.ResetOptions{Commit: to, Mode: git.HardReset})
+}
+
+func (g *GoGitBackend) ToString() string {
+	return "go-git"
+}
@@ -0,0 +1,116 @@
+package check
+
+import (
+	"fmt"
+	"maps"
+	"path"
+	"path/filepath"
+	"slices"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// ProbedRemotes are branch and tag tips advertised by remotes
+type ProbedRemotes struct {
+	// Branches are named as remote-tracking branches, e.g. refs/remotes/origin/main
+	Branches []*plumbing.Reference
+	Tags     map[plumbing.ReferenceName][]plumbing.Hash
+	// Remotes are names of all remotes, true for those which were probed
+	Remotes map[string]bool
+	// Errors are errors of remotes which could not be probed, other remotes are probed anyway
+	Errors []error
+}
+
+// ProbeRemotes lists tips of selected remotes without fetching objects or updating remote-tracking branches
+func (f *FetcherChecker) ProbeRemotes(repo *git.Repository) (ProbedRemotes, error) {
+	probed := ProbedRemotes{
+		Tags:    make(map[plumbing.ReferenceName][]plumbing.Hash),
+		Remotes: make(map[string]bool),
+	}
+	remotes, err := repo.Remotes()
+	if err != nil {
+		return probed, fmt.Errorf("error checking remotes %s", err)
+	}
+	for _, remote := range remotes {
+		remoteName := remote.Config().Name
+		probed.Remotes[remoteName] = false
+		urls := remote.Config().URLs
+		if len(urls) == 0 || !f.NeedsFetch(remoteName, urls[0]) {
+			continue
+		}
+		probeAuth, err := f.AuthFor(urls[0])
+		if err != nil {
+			probed.Errors = append(probed.Errors, fmt.Errorf("error getting credentials for remote %s\n%s", remoteName, err))
+			continue
+		}
+		references, err := f.Scheduler.List(remote, &git.ListOptions{Auth: probeAuth}, urls[0])
+		if err != nil {
+			probed.Errors = append(probed.Errors, fmt.Errorf("error probing remote %s\n%s", remoteName, err))
+			continue
+		}
+		probed.Remotes[remoteName] = true
+		for _, ref := range references {
+			if ref.Type() != plumbing.HashReference {
+				continue
+			}
+			if ref.Name().IsBranch() {
+				probed.Branches = append(probed.Branches, plumbing.NewHashReference(
+					plumbing.NewRemoteReferenceName(remoteName, ref.Name().Short()),
+					ref.Hash(),
+				))
+			} else if ref.Name().IsTag() {
+				probed.Tags[ref.Name()] = append(probed.Tags[ref.Name()], ref.Hash())
+			}
+		}
+	}
+	return probed, nil
+}
+
+// IsProbed reports whether the remote-tracking branch belongs to a probed remote,
+// remote names may contain "/" so the longest matching remote name is taken
+func (p ProbedRemotes) IsProbed(reference plumbing.ReferenceName) bool {
+	remote, _, _ := splitRemoteRef(reference, slices.Collect(maps.Keys(p.Remotes)))
+	return p.Remotes[remote]
+}
+
+// AnyProbed reports whether at least one remote was probed
+func (p ProbedRemotes) AnyProbed() bool {
+	for _, ok := range p.Remotes {
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+func newUnpushedTag(directory, repository string, tag *plumbing.Reference) UnpushedTag {
+	return UnpushedTag{
+		directory:  directory,
+		repository: repository,
+		tagName:    tag.Name().Short(),
+	}
+}
+
+// UnpushedTag is a local tag missing on probed remotes or pointing elsewhere there
+type UnpushedTag struct {
+	directory  string
+	repository string
+	tagName    string
+}
+
+func (u UnpushedTag) Repository() string {
+	return u.repository
+}
+
+func (u UnpushedTag) RepositoryPath() string {
+	return path.Join(path.Base(u.directory), u.repository)
+}
+
+func (u UnpushedTag) FullPath() string {
+	return filepath.Join(u.directory, u.repository)
+}
+
+func (u UnpushedTag) TagName() string {
+	return u.tagName
+}
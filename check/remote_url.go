@@ -0,0 +1,53 @@
+package check
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+)
+
+// RemoteUrl is a remote URL split into the parts fetch selectors match
+type RemoteUrl struct {
+	// Host is the host name without user and port, empty for local remotes
+	Host string
+	// Path is the owner path, e.g. "group/subgroup" for "group/subgroup/repo.git"
+	Path string
+	// Name is the repository name without ".git" suffix
+	Name string
+}
+
+// Group is the innermost group (organization/user) owning the repository
+func (u RemoteUrl) Group() string {
+	return path.Base(u.Path)
+}
+
+// ParseRemoteUrl parses scp-like, ssh://, git://, http(s):// and file:// remote URLs and local paths
+func ParseRemoteUrl(remoteUrl string) (RemoteUrl, error) {
+	endpoint, err := transport.NewEndpoint(remoteUrl)
+	if err != nil {
+		return RemoteUrl{}, fmt.Errorf("invalid remote url %s\n%s", remoteUrl, err)
+	}
+	segments := strings.FieldsFunc(endpoint.Path, func(char rune) bool {
+		return char == '/'
+	})
+	if len(segments) == 0 {
+		return RemoteUrl{}, fmt.Errorf("remote url %s has no repository path", remoteUrl)
+	}
+
+	// Azure DevOps puts "_git" before the repository name in https urls
+	// and prefixes ssh paths with the api version
+	if len(segments) > 1 && segments[len(segments)-2] == "_git" {
+		segments = append(segments[:len(segments)-2], segments[len(segments)-1])
+	}
+	if endpoint.Host == "ssh.dev.azure.com" && len(segments) > 1 && segments[0] == "v3" {
+		segments = segments[1:]
+	}
+
+	return RemoteUrl{
+		Host: endpoint.Host,
+		Path: strings.Join(segments[:len(segments)-1], "/"),
+		Name: strings.TrimSuffix(segments[len(segments)-1], ".git"),
+	}, nil
+}
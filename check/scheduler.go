@@ -0,0 +1,243 @@
+package check
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+)
+
+// transientMessages are messages of connection failures which transports report without typed errors
+var transientMessages = []string{
+	"connection refused",
+	"connection reset",
+	"i/o timeout",
+	"timed out",
+}
+
+// FetchScheduler limits the number of concurrent fetches overall and per host,
+// it retries transient failures with exponential backoff
+type FetchScheduler struct {
+	timeout   time.Duration
+	retries   int
+	backoff   time.Duration
+	hostLimit int
+
+	jobs  chan struct{}
+	hosts map[string]chan struct{}
+	mutex sync.Mutex
+
+	progress *fetchProgress
+}
+
+func NewFetchScheduler(jobs, hostJobs, retries int, timeout time.Duration) *FetchScheduler {
+	if jobs < 1 {
+		jobs = 1
+	}
+	if hostJobs < 1 {
+		hostJobs = 1
+	}
+	return &FetchScheduler{
+		timeout:   timeout,
+		retries:   retries,
+		backoff:   time.Second,
+		hostLimit: hostJobs,
+		jobs:      make(chan struct{}, jobs),
+		hosts:     make(map[string]chan struct{}),
+		progress:  newFetchProgress(os.Stderr),
+	}
+}
+
+// Fetch fetches the remote once slots for it are available, already up-to-date remote is not an error
+func (s *FetchScheduler) Fetch(
+	repo *git.Repository,
+	options *git.FetchOptions,
+	remoteUrl string,
+) error {
+	return s.run(endpointHost(remoteUrl), func(ctx context.Context) error {
+		err := repo.FetchContext(ctx, options)
+		if errors.Is(err, git.NoErrAlreadyUpToDate) {
+			return nil
+		}
+		return err
+	})
+}
+
+// List lists references advertised by the remote, as `git ls-remote` does, once slots for it are available
+func (s *FetchScheduler) List(
+	remote *git.Remote,
+	options *git.ListOptions,
+	remoteUrl string,
+) ([]*plumbing.Reference, error) {
+	var references []*plumbing.Reference
+	err := s.run(endpointHost(remoteUrl), func(ctx context.Context) error {
+		var err error
+		references, err = remote.ListContext(ctx, options)
+		return err
+	})
+	return references, err
+}
+
+func endpointHost(remoteUrl string) string {
+	endpoint, err := transport.NewEndpoint(remoteUrl)
+	if err != nil {
+		return ""
+	}
+	return endpoint.Host
+}
+
+func (s *FetchScheduler) hostSlots(host string) chan struct{} {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	slots, found := s.hosts[host]
+	if !found {
+		slots = make(chan struct{}, s.hostLimit)
+		s.hosts[host] = slots
+	}
+	return slots
+}
+
+func (s *FetchScheduler) run(host string, fetch func(ctx context.Context) error) error {
+	s.progress.update(func() { s.progress.queued++ })
+
+	var err error
+	for attempt := 0; attempt <= s.retries; attempt++ {
+		// slots are released during the backoff, so a retrying host does not hold back others
+		if attempt > 0 {
+			time.Sleep(s.backoff << (attempt - 1))
+		}
+		err = s.attemptWithSlots(host, fetch)
+		if err == nil || !isTransient(err) {
+			break
+		}
+	}
+
+	s.progress.update(func() {
+		s.progress.done++
+		if err != nil {
+			s.progress.failed++
+		}
+	})
+	return err
+}
+
+// attemptWithSlots runs one attempt holding a slot of the host and a global job slot
+func (s *FetchScheduler) attemptWithSlots(host string, fetch func(ctx context.Context) error) error {
+	// host slot is taken first, so waiting for a busy host does not block other hosts
+	hostSlots := s.hostSlots(host)
+	hostSlots <- struct{}{}
+	s.jobs <- struct{}{}
+	s.progress.update(func() { s.progress.running++ })
+	defer func() {
+		<-s.jobs
+		<-hostSlots
+		s.progress.update(func() { s.progress.running-- })
+	}()
+	return s.attempt(fetch)
+}
+
+func (s *FetchScheduler) attempt(fetch func(ctx context.Context) error) error {
+	ctx := context.Background()
+	if s.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.timeout)
+		defer cancel()
+	}
+	err := fetch(ctx)
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("fetch timed out after %s: %w", s.timeout, context.DeadlineExceeded)
+	}
+	return err
+}
+
+// isTransient reports whether retrying could fix the fetch error: timeouts, refused or reset
+// connections and HTTP 5xx or 429 responses, any other error is permanent
+func isTransient(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNRESET) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	// go-git wraps HTTP errors without unwrapping them
+	var unexpected *plumbing.UnexpectedError
+	if errors.As(err, &unexpected) {
+		err = unexpected.Err
+	}
+	var httpErr *githttp.Err
+	if errors.As(err, &httpErr) {
+		status := httpErr.StatusCode()
+		return status >= http.StatusInternalServerError || status == http.StatusTooManyRequests
+	}
+	message := strings.ToLower(err.Error())
+	for _, transient := range transientMessages {
+		if strings.Contains(message, transient) {
+			return true
+		}
+	}
+	return false
+}
+
+// Finish clears the progress line
+func (s *FetchScheduler) Finish() {
+	s.progress.clear()
+}
+
+// fetchProgress prints a progress line to a terminal, it is silent otherwise
+type fetchProgress struct {
+	writer  io.Writer
+	enabled bool
+	mutex   sync.Mutex
+
+	queued  int
+	running int
+	done    int
+	failed  int
+}
+
+func newFetchProgress(file *os.File) *fetchProgress {
+	info, err := file.Stat()
+	return &fetchProgress{
+		writer:  file,
+		enabled: err == nil && info.Mode()&os.ModeCharDevice != 0,
+	}
+}
+
+func (p *fetchProgress) update(change func()) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	change()
+	if p.enabled {
+		_, _ = fmt.Fprintf(
+			p.writer,
+			"\r\033[Kfetching: %d/%d done, %d running, %d failed",
+			p.done,
+			p.queued,
+			p.running,
+			p.failed,
+		)
+	}
+}
+
+func (p *fetchProgress) clear() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.enabled && p.queued > 0 {
+		_, _ = fmt.Fprint(p.writer, "\r\033[K")
+	}
+}
@@ -0,0 +1,189 @@
+package check
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+)
+
+func newTestScheduler(jobs, hostJobs, retries int, timeout time.Duration) *FetchScheduler {
+	scheduler := NewFetchScheduler(jobs, hostJobs, retries, timeout)
+	scheduler.backoff = time.Millisecond
+	scheduler.progress = &fetchProgress{writer: io.Discard}
+	return scheduler
+}
+
+func TestSchedulerHostLimit(t *testing.T) {
+	scheduler := newTestScheduler(8, 2, 0, 0)
+	var running, maxRunning atomic.Int32
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = scheduler.run("example.com", func(ctx context.Context) error {
+				current := running.Add(1)
+				for {
+					previous := maxRunning.Load()
+					if current <= previous || maxRunning.CompareAndSwap(previous, current) {
+						break
+					}
+				}
+				time.Sleep(5 * time.Millisecond)
+				running.Add(-1)
+				return nil
+			})
+		}()
+	}
+	wg.Wait()
+	if maxRunning.Load() != 2 {
+		t.Errorf(`Should run at most 2 fetches per host, ran %d`, maxRunning.Load())
+	}
+}
+
+func TestSchedulerRetries(t *testing.T) {
+	scheduler := newTestScheduler(1, 1, 2, 0)
+
+	attempts := 0
+	err := scheduler.run("example.com", func(ctx context.Context) error {
+		attempts++
+		if attempts < 3 {
+			return errors.New("connection reset by peer")
+		}
+		return nil
+	})
+	if err != nil || attempts != 3 {
+		t.Errorf(`Should succeed on third attempt, got %v after %d attempts`, err, attempts)
+	}
+
+	attempts = 0
+	err = scheduler.run("example.com", func(ctx context.Context) error {
+		attempts++
+		return transport.ErrAuthenticationRequired
+	})
+	if !errors.Is(err, transport.ErrAuthenticationRequired) || attempts != 1 {
+		t.Errorf(`Should not retry permanent errors, got %v after %d attempts`, err, attempts)
+	}
+}
+
+func TestSchedulerBackoffReleasesSlots(t *testing.T) {
+	scheduler := newTestScheduler(1, 1, 1, 0)
+	scheduler.backoff = 200 * time.Millisecond
+
+	failed := make(chan struct{})
+	retried := make(chan struct{})
+	go func() {
+		attempts := 0
+		_ = scheduler.run("example.com", func(ctx context.Context) error {
+			attempts++
+			if attempts == 1 {
+				close(failed)
+				return errors.New("connection reset by peer")
+			}
+			close(retried)
+			return nil
+		})
+	}()
+	<-failed
+
+	done := make(chan struct{})
+	go func() {
+		_ = scheduler.run("example.com", func(ctx context.Context) error { return nil })
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-retried:
+		t.Error(`Should run other fetches of the host during the backoff`)
+	}
+	<-retried
+}
+
+func TestIsTransient(t *testing.T) {
+	httpError := func(status int) error {
+		return plumbing.NewUnexpectedError(&githttp.Err{Response: &http.Response{StatusCode: status}})
+	}
+	tests := []struct {
+		err       error
+		transient bool
+	}{
+		{fmt.Errorf("fetch timed out: %w", context.DeadlineExceeded), true},
+		{&net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}, true},
+		{errors.New("read: connection reset by peer"), true},
+		{httpError(http.StatusServiceUnavailable), true},
+		{httpError(http.StatusTooManyRequests), true},
+		{httpError(http.StatusBadRequest), false},
+		{transport.ErrAuthenticationRequired, false},
+		{context.Canceled, false},
+		{errors.New("object not found"), false},
+	}
+	for _, test := range tests {
+		if isTransient(test.err) != test.transient {
+			t.Errorf(`isTransient(%v) should be %t`, test.err, test.transient)
+		}
+	}
+}
+
+func TestSchedulerTimeout(t *testing.T) {
+	scheduler := newTestScheduler(1, 1, 1, 10*time.Millisecond)
+
+	attempts := 0
+	err := scheduler.run("example.com", func(ctx context.Context) error {
+		attempts++
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	if !errors.Is(err, context.DeadlineExceeded) || attempts != 2 {
+		t.Errorf(`Should time out twice, got %v after %d attempts`, err, attempts)
+	}
+}
+
+func TestSchedulerFetch(t *testing.T) {
+	origin := newTestRepository(t)
+	directory := filepath.Join(t.TempDir(), "clone")
+	runGitCommand(t, filepath.Dir(directory), "clone", "file://"+origin, directory)
+	writeFile(t, filepath.Join(origin, "modified.txt"), "not pulled")
+	runGitCommand(t, origin, "commit", "-am", "not pulled")
+
+	repo, err := git.PlainOpen(directory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	scheduler := newTestScheduler(1, 1, 0, time.Minute)
+	for range 2 {
+		err = scheduler.Fetch(repo, &git.FetchOptions{RemoteName: "origin"}, "file://"+origin)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	originRepo, err := git.PlainOpen(origin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	originHead, err := originRepo.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+	remoteRef, err := repo.Reference(plumbing.NewRemoteReferenceName("origin", "master"), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if remoteRef.Hash() != originHead.Hash() {
+		t.Errorf(`Remote branch should be fetched, got %s instead of %s`, remoteRef.Hash(), originHead.Hash())
+	}
+}
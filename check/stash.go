@@ -4,6 +4,7 @@ import (
 	"io"
 	"iter"
 	"path"
+	"path/filepath"
 
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing/object"
@@ -63,9 +64,8 @@ func (s *StashChecker) Check(
 }
 
 func newStashedChanges(directory, repository string, firstParent *object.Commit) StashedChanges {
-	base := path.Base(directory)
 	return StashedChanges{
-		base:             base,
+		directory:        directory,
 		repository:       repository,
 		commitUnderStash: firstParent,
 	}
@@ -76,7 +76,7 @@ func (s *StashChecker) ToString() string {
 }
 
 type StashedChanges struct {
-	base             string
+	directory        string
 	repository       string
 	commitUnderStash *object.Commit
 }
@@ -86,7 +86,11 @@ func (u StashedChanges) Repository() string {
 }
 
 func (u StashedChanges) RepositoryPath() string {
-	return path.Join(u.base, u.repository)
+	return path.Join(path.Base(u.directory), u.repository)
+}
+
+func (u StashedChanges) FullPath() string {
+	return filepath.Join(u.directory, u.repository)
 }
 
 func (u StashedChanges) CommitUnderStash() *object.Commit {
@@ -4,72 +4,133 @@ import (
 	"fmt"
 	"io/fs"
 	"iter"
+	"maps"
 	"os"
 	"path"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/go-git/go-git/v5"
 	"github.com/hov1417/assayer/arguments"
+	"github.com/hov1417/assayer/cache"
 	"github.com/hov1417/assayer/types"
 )
 
 type WorkTreeChecker struct {
-	modified  bool
-	untracked bool
+	staged     bool
+	unstaged   bool
+	conflicted bool
+	untracked  bool
+	useCache   bool
+	backend    Backend
+	files      bool
+	filesLimit int
 }
 
 func NewWorkTreeChecker(
 	arguments arguments.Arguments,
 ) *WorkTreeChecker {
-	if !arguments.Modified && !arguments.Untracked {
+	if !arguments.Staged && !arguments.Unstaged && !arguments.Conflicted && !arguments.Untracked {
 		return nil
 	}
 	return &WorkTreeChecker{
-		modified:  arguments.Modified,
-		untracked: arguments.Untracked,
+		staged:     arguments.Staged,
+		unstaged:   arguments.Unstaged,
+		conflicted: arguments.Conflicted,
+		untracked:  arguments.Untracked,
+		useCache:   !arguments.NoCache,
+		backend:    NewBackend(arguments.Backend),
+		files:      arguments.Files,
+		filesLimit: arguments.FilesLimit,
 	}
 }
 
 type StatusHolder struct {
-	status *git.Status
+	status   *git.Status
+	useCache bool
+	backend  Backend
 }
 
-func (s *StatusHolder) getStatus(repository string, repo *git.Repository) (*git.Status, error) {
-	if s.status == nil {
-		tree, err := repo.Worktree()
-		if err != nil {
-			return nil, fmt.Errorf("error checking repository worktree %s\n%s", repository, err)
-		}
-		status, err := tree.Status()
+func (s *StatusHolder) getStatus(
+	directory, repository string,
+	repo *git.Repository,
+) (*git.Status, error) {
+	if s.status != nil {
+		return s.status, nil
+	}
+
+	fullPath := filepath.Join(directory, repository)
+	if !s.useCache {
+		status, err := s.worktreeStatus(fullPath, repository, repo)
 		if err != nil {
-			return nil, fmt.Errorf("error checking repository status %s\n%s", repository, err)
+			return nil, err
 		}
 		s.status = &status
+		return s.status, nil
 	}
 
+	fingerprint, err := cache.Fingerprint(fullPath, ExcludesFile(repo))
+	if err != nil {
+		return nil, fmt.Errorf("error fingerprinting repository %s\n%s", repository, err)
+	}
+	// statuses differ between backends, e.g. in collapsing untracked directories
+	fingerprint = s.backend.ToString() + ":" + fingerprint
+	status, found := cache.LoadStatus(fullPath, fingerprint)
+	if !found {
+		status, err = s.worktreeStatus(fullPath, repository, repo)
+		if err != nil {
+			return nil, err
+		}
+		err = cache.SaveStatus(fullPath, fingerprint, status)
+		if err != nil {
+			return nil, fmt.Errorf("error caching repository status %s\n%s", repository, err)
+		}
+	}
+	s.status = &status
 	return s.status, nil
 }
 
+func (s *StatusHolder) worktreeStatus(
+	fullPath, repository string,
+	repo *git.Repository,
+) (git.Status, error) {
+	status, err := s.backend.Status(fullPath, repo)
+	if err != nil {
+		return nil, fmt.Errorf("error checking repository status %s\n%s", repository, err)
+	}
+	return status, nil
+}
+
 func (w *WorkTreeChecker) Check(
 	directory, repository string,
 	repo *git.Repository,
 ) iter.Seq[types.Response] {
 	return func(yield func(types.Response) bool) {
-		statusHolder := StatusHolder{status: nil}
-		if w.modified {
-			status, err := statusHolder.getStatus(repository, repo)
+		statusHolder := StatusHolder{status: nil, useCache: w.useCache, backend: w.backend}
+		if w.files {
+			status, err := statusHolder.getStatus(directory, repository, repo)
+			if err != nil {
+				yield(types.Response{Err: err})
+				return
+			}
+			w.checkFiles(directory, repository, *status, repo, yield)
+			return
+		}
+
+		if w.staged || w.unstaged || w.conflicted {
+			status, err := statusHolder.getStatus(directory, repository, repo)
 			if err != nil {
 				yield(types.Response{Err: err})
 				return
 			}
-			if !checkModified(directory, repository, *status, yield) {
+			if !w.checkModified(directory, repository, *status, yield) {
 				return
 			}
 		}
 
 		if w.untracked {
-			status, err := statusHolder.getStatus(repository, repo)
+			status, err := statusHolder.getStatus(directory, repository, repo)
 			if err != nil {
 				yield(types.Response{Err: err})
 				return
@@ -85,76 +146,159 @@ func (w *WorkTreeChecker) ToString() string {
 	return "WorkTreeCheck"
 }
 
-type Modified struct {
-	base             string
+// FileChange is a changed file of the worktree, embedded by Staged, Unstaged and Conflicted
+type FileChange struct {
+	directory        string
 	repository       string
 	modifiedItem     string
 	modificationType git.StatusCode
+	staging          git.StatusCode
+	worktree         git.StatusCode
+	originalItem     string
 }
 
-func (u Modified) Repository() string {
+func (u FileChange) Repository() string {
 	return u.repository
 }
 
-func (u Modified) RepositoryPath() string {
-	return path.Join(u.base, u.repository)
+func (u FileChange) RepositoryPath() string {
+	return path.Join(path.Base(u.directory), u.repository)
 }
 
-func (u Modified) ModifiedItem() string {
+func (u FileChange) FullPath() string {
+	return filepath.Join(u.directory, u.repository)
+}
+
+func (u FileChange) ModifiedItem() string {
 	return u.modifiedItem
 }
 
-func (u Modified) ModificationType() git.StatusCode {
+func (u FileChange) ModificationType() git.StatusCode {
 	return u.modificationType
 }
 
+func (u FileChange) Staging() git.StatusCode {
+	return u.staging
+}
+
+func (u FileChange) Worktree() git.StatusCode {
+	return u.worktree
+}
+
+// OriginalItem is the path before rename or copy, empty otherwise
+func (u FileChange) OriginalItem() string {
+	return u.originalItem
+}
+
+// Staged is a change added to the index but not committed
+type Staged struct {
+	FileChange
+}
+
+// Unstaged is a change of the worktree not added to the index
+type Unstaged struct {
+	FileChange
+}
+
+// Conflicted is a file left unmerged after a merge conflict
+type Conflicted struct {
+	FileChange
+}
+
+func isChanged(status git.StatusCode) bool {
+	return status != git.Untracked && status != git.Unmodified
+}
+
+func isConflicted(fileStatus *git.FileStatus) bool {
+	return fileStatus.Staging == git.UpdatedButUnmerged ||
+		fileStatus.Worktree == git.UpdatedButUnmerged
+}
+
+// fileVerdicts classifies a changed file, a file can be both staged and unstaged
+func (w *WorkTreeChecker) fileVerdicts(
+	directory, repository string,
+	itemPath string,
+	fileStatus *git.FileStatus,
+) []types.Verdict {
+	var verdicts []types.Verdict
+	if isConflicted(fileStatus) {
+		if w.conflicted {
+			change := newFileChange(
+				directory, repository, itemPath, git.UpdatedButUnmerged, fileStatus,
+			)
+			verdicts = append(verdicts, Conflicted{change})
+		}
+		return verdicts
+	}
+	if w.staged && isChanged(fileStatus.Staging) {
+		change := newFileChange(
+			directory, repository, itemPath, fileStatus.Staging, fileStatus,
+		)
+		verdicts = append(verdicts, Staged{change})
+	}
+	if w.unstaged && isChanged(fileStatus.Worktree) {
+		change := newFileChange(
+			directory, repository, itemPath, fileStatus.Worktree, fileStatus,
+		)
+		verdicts = append(verdicts, Unstaged{change})
+	}
+	return verdicts
+}
+
 // returns value indicating to "continue" or not
-func checkModified(
+func (w *WorkTreeChecker) checkModified(
 	directory, repository string,
 	status git.Status,
 	yield func(types.Response) bool,
 ) bool {
-	var modifiedItem string
-	var modificationType git.StatusCode
-	for itemPath, s := range status {
-		if s.Worktree != git.Untracked && s.Worktree != git.Unmodified {
-			modifiedItem = itemPath
-			modificationType = s.Worktree
-			break
-		}
-		if s.Staging != git.Untracked && s.Staging != git.Unmodified {
-			modifiedItem = itemPath
-			modificationType = s.Staging
-			break
+	var conflicted, staged, unstaged types.Verdict
+	// the first file in order is reported, so that reports do not change between runs
+	for _, itemPath := range slices.Sorted(maps.Keys(status)) {
+		for _, verdict := range w.fileVerdicts(directory, repository, itemPath, status[itemPath]) {
+			switch verdict.(type) {
+			case Conflicted:
+				if conflicted == nil {
+					conflicted = verdict
+				}
+			case Staged:
+				if staged == nil {
+					staged = verdict
+				}
+			case Unstaged:
+				if unstaged == nil {
+					unstaged = verdict
+				}
+			}
 		}
 	}
 
-	if len(modifiedItem) != 0 {
-		return yield(
-			types.Response{
-				Verdict: newModified(directory, repository, modifiedItem, modificationType),
-			},
-		)
+	for _, verdict := range []types.Verdict{conflicted, staged, unstaged} {
+		if verdict != nil && !yield(types.Response{Verdict: verdict}) {
+			return false
+		}
 	}
 	return true
 }
 
-func newModified(
+func newFileChange(
 	directory, repository string,
 	modifiedItem string,
 	modificationType git.StatusCode,
-) Modified {
-	base := path.Base(directory)
-	return Modified{
-		base:             base,
+	fileStatus *git.FileStatus,
+) FileChange {
+	return FileChange{
+		directory:        directory,
 		repository:       repository,
 		modifiedItem:     modifiedItem,
 		modificationType: modificationType,
+		staging:          fileStatus.Staging,
+		worktree:         fileStatus.Worktree,
+		originalItem:     fileStatus.Extra,
 	}
 }
 
 type Untracked struct {
-	base          string
+	directory     string
 	repository    string
 	untrackedItem string
 }
@@ -164,7 +308,11 @@ func (u Untracked) Repository() string {
 }
 
 func (u Untracked) RepositoryPath() string {
-	return path.Join(u.base, u.repository)
+	return path.Join(path.Base(u.directory), u.repository)
+}
+
+func (u Untracked) FullPath() string {
+	return filepath.Join(u.directory, u.repository)
 }
 
 func (u Untracked) UntrackedItem() string {
@@ -179,8 +327,8 @@ func checkUntracked(
 	yield func(types.Response) bool,
 ) bool {
 	var untrackedItem string
-	for itemPath, s := range status {
-		if s.Worktree == git.Untracked {
+	for _, itemPath := range slices.Sorted(maps.Keys(status)) {
+		if status[itemPath].Worktree == git.Untracked {
 			untrackedItem = itemPath
 			break
 		}
@@ -249,9 +397,8 @@ func checkUntracked(
 }
 
 func newUntracked(directory, repository string, untrackedItem string) Untracked {
-	base := path.Base(directory)
 	return Untracked{
-		base:          base,
+		directory:     directory,
 		repository:    repository,
 		untrackedItem: untrackedItem,
 	}
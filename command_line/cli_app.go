@@ -3,14 +3,17 @@ package command_line
 import (
 	"fmt"
 	"os"
+	"slices"
 	"text/template"
+	"time"
 
 	"github.com/gobwas/glob"
 	"github.com/hov1417/assayer/arguments"
+	"github.com/hov1417/assayer/cache"
 	"github.com/urfave/cli/v2"
 )
 
-func App(action func(c *cli.Context) error) *cli.App {
+func App(action func(c *cli.Context) error, commands ...*cli.Command) *cli.App {
 	cli.VersionFlag = &cli.BoolFlag{
 		Name:    "version",
 		Aliases: []string{"V"},
@@ -27,95 +30,57 @@ func App(action func(c *cli.Context) error) *cli.App {
 		UseShortOptionHandling: true,
 		UsageText:              "assayer [options] [path-to-check]",
 		Version:                "0.9.1",
-		Flags: []cli.Flag{
-			&cli.BoolFlag{Name: "all", Usage: "Check all in repositories", Aliases: []string{"a"}},
-
-			&cli.BoolFlag{
-				Category: "Check Type",
-				Name:     "unmodified",
-				Usage:    "Show repositories where nothing is changed",
-				Aliases:  []string{"u"},
-			},
-			&cli.BoolFlag{
-				Category: "Check Type",
-				Name:     "modified",
-				Usage:    "Check if worktree is changed",
-				Aliases:  []string{"m"},
-			},
-			&cli.BoolFlag{
-				Category: "Check Type",
-				Name:     "untracked",
-				Usage:    "Check if there are untracked files",
-				Aliases:  []string{"t"},
-			},
-			&cli.BoolFlag{
-				Category: "Check Type",
-				Name:     "stashed",
-				Usage:    "Check if there are stashed changes",
-				Aliases:  []string{"s"},
-			},
-			&cli.BoolFlag{
-				Category: "Check Type",
-				Name:     "behind-branches",
-				Usage:    "Check if there are branches that are behind remote",
-				Aliases:  []string{"b"},
-			},
-			&cli.BoolFlag{
-				Category: "Check Type",
-				Name:     "ahead-branches",
-				Usage:    "Check if there are branches that are ahead remote",
-				Aliases:  []string{"A"},
-			},
-			&cli.BoolFlag{
-				Category: "Check Type",
-				Name:     "local-only-branches",
-				Usage:    "Check if there are local only branches",
-				Aliases:  []string{"l"},
-			},
-
-			&cli.BoolFlag{
-				Name:    "nested",
-				Usage:   "Check repositories in repositories",
-				Aliases: []string{"n"},
-			},
-			&cli.BoolFlag{
-				Name:    "count",
-				Usage:   "Counted report",
-				Aliases: []string{"c"},
-			},
-			&cli.StringFlag{
-				Name:    "exclude",
-				Usage:   "Exclude glob pattern",
-				Aliases: []string{"e"},
-			},
-			&cli.BoolFlag{
-				Name:    "deep",
-				Usage:   "Check everything, by default only first found info will be reported.\n\tChecks are in order [modified, untracked, stash, local only branch, remote ahead, remote behind]\n\t",
-				Aliases: []string{"d"},
-			},
-			&cli.BoolFlag{
-				Name:    "verbose",
-				Usage:   "Provide detailed information in the report",
-				Aliases: []string{"v"},
-			},
-			&cli.StringFlag{
-				Name:    "reporter",
-				Usage:   "Provide reporter's template",
-				Aliases: []string{"r"},
+		Flags: slices.Concat(
+			checkTypeFlags(),
+			[]cli.Flag{
+				&cli.BoolFlag{
+					Name:    "count",
+					Usage:   "Counted report",
+					Aliases: []string{"c"},
+				},
+				&cli.BoolFlag{
+					Name:    "deep",
+					Usage:   "Check everything, by default only first found info will be reported.\n\tChecks are in order [conflicted, staged, unstaged, untracked, stash, local only branch, remote ahead, remote behind]\n\t",
+					Aliases: []string{"d"},
+				},
+				&cli.BoolFlag{
+					Name:    "verbose",
+					Usage:   "Provide detailed information in the report",
+					Aliases: []string{"v"},
+				},
+				&cli.BoolFlag{
+					Name:    "files",
+					Usage:   "Report every modified, staged and untracked file with its staging and worktree status",
+					Aliases: []string{"all-files"},
+				},
+				&cli.IntFlag{
+					Name:  "files-limit",
+					Usage: "Maximum number of files reported per repository with --files, 0 for no limit",
+					Value: 50,
+				},
+				&cli.StringFlag{
+					Name:    "reporter",
+					Usage:   "Provide reporter's template",
+					Aliases: []string{"r"},
+				},
+				&cli.StringFlag{
+					Name:  "format",
+					Usage: "Output format, \"default\", \"table\", \"tree\", \"count\", \"template\" for --reporter, \"prometheus\" for metrics of the node_exporter textfile collector, \"junit\" or \"sarif\"",
+					Value: "default",
+				},
+				&cli.StringFlag{
+					Name:  "sort",
+					Usage: "Order of verdicts, \"path\", \"kind\" or \"age\" for the oldest work first",
+					Value: "path",
+				},
+				&cli.BoolFlag{
+					Name:  "stream",
+					Usage: "Print verdicts as soon as they are found, in no particular order",
+				},
 			},
-
-			&cli.BoolFlag{
-				Category: "Fetch",
-				Name:     "fetch-all",
-				Usage:    "Fetch all repositories before checking",
-				Aliases:  []string{"f"},
-			},
-			&cli.StringFlag{
-				Category: "Fetch",
-				Name:     "fetch-group",
-				Usage:    "Fetch groups (organization/user) repositories before checking, value is a glob pattern",
-			},
-		},
+			repositoryFlags(),
+			fetchFlags(),
+		),
 		CommandNotFound: func(c *cli.Context, command string) {
 			println("Command " + command + " not found")
 			cli.ShowAppHelpAndExit(c, 2)
@@ -124,9 +89,201 @@ func App(action func(c *cli.Context) error) *cli.App {
 			cli.ShowAppHelpAndExit(c, 1)
 			return err
 		},
-		Action: action,
+		Action:   action,
+		Commands: commands,
+	}
+
+}
+
+// checkTypeFlags select checks, they are shared with the tui subcommand
+func checkTypeFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.BoolFlag{Name: "all", Usage: "Check all in repositories", Aliases: []string{"a"}},
+
+		&cli.BoolFlag{
+			Category: "Check Type",
+			Name:     "unmodified",
+			Usage:    "Show repositories where nothing is changed",
+			Aliases:  []string{"u"},
+		},
+
+		&cli.BoolFlag{
+			Category: "Check Type",
+			Name:     "modified",
+			Usage:    "Check if worktree is changed, same as --staged --unstaged --conflicted",
+			Aliases:  []string{"m"},
+		},
+		&cli.BoolFlag{
+			Category: "Check Type",
+			Name:     "staged",
+			Usage:    "Check if there are staged but not committed changes",
+			Aliases:  []string{"S"},
+		},
+		&cli.BoolFlag{
+			Category: "Check Type",
+			Name:     "unstaged",
+			Usage:    "Check if there are changes not added to the index",
+			Aliases:  []string{"U"},
+		},
+		&cli.BoolFlag{
+			Category: "Check Type",
+			Name:     "conflicted",
+			Usage:    "Check if there are unresolved merge conflicts",
+			Aliases:  []string{"C"},
+		},
+		&cli.BoolFlag{
+			Category: "Check Type",
+			Name:     "untracked",
+			Usage:    "Check if there are untracked files",
+			Aliases:  []string{"t"},
+		},
+		&cli.BoolFlag{
+			Category: "Check Type",
+			Name:     "stashed",
+			Usage:    "Check if there are stashed changes",
+			Aliases:  []string{"s"},
+		},
+		&cli.BoolFlag{
+			Category: "Check Type",
+			Name:     "behind-branches",
+			Usage:    "Check if there are branches that are behind remote",
+			Aliases:  []string{"b"},
+		},
+		&cli.BoolFlag{
+			Category: "Check Type",
+			Name:     "ahead-branches",
+			Usage:    "Check if there are branches that are ahead remote",
+			Aliases:  []string{"A"},
+		},
+		&cli.BoolFlag{
+			Category: "Check Type",
+			Name:     "local-only-branches",
+			Usage:    "Check if there are local only branches",
+			Aliases:  []string{"l"},
+		},
+	}
+}
+
+// repositoryFlags select and read repositories, they are shared with subcommands
+func repositoryFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.BoolFlag{
+			Name:    "nested",
+			Usage:   "Check repositories in repositories",
+			Aliases: []string{"n"},
+		},
+		&cli.StringFlag{
+			Name:    "exclude",
+			Usage:   "Exclude glob pattern",
+			Aliases: []string{"e"},
+		},
+		&cli.BoolFlag{
+			Name:  "rescan",
+			Usage: "Walk all directories, ignoring the cached repository index",
+		},
+		&cli.BoolFlag{
+			Name:  "no-cache",
+			Usage: "Compute worktree status of every repository, ignoring cached results",
+		},
+		&cli.StringFlag{
+			Name:    "backend",
+			Usage:   "Backend used to read repositories, \"go-git\" or \"git\" for the system git binary",
+			Value:   "go-git",
+			EnvVars: []string{"ASSAYER_BACKEND"},
+		},
+	}
+}
+
+// fetchFlags are shared with subcommands, which check remotes before acting
+func fetchFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.BoolFlag{
+			Category: "Fetch",
+			Name:     "fetch-all",
+			Usage:    "Fetch all repositories before checking",
+			Aliases:  []string{"f"},
+		},
+		&cli.StringFlag{
+			Category: "Fetch",
+			Name:     "fetch-group",
+			Usage:    "Fetch groups (organization/user) repositories before checking, value is a glob pattern",
+		},
+		&cli.StringFlag{
+			Category: "Fetch",
+			Name:     "fetch-host",
+			Usage:    "Fetch repositories of remote hosts matching the glob pattern, e.g. \"*.example.com\"",
+		},
+		&cli.StringFlag{
+			Category: "Fetch",
+			Name:     "fetch-path",
+			Usage:    "Fetch repositories whose full owner path matches the glob pattern, e.g. \"group/**\"",
+		},
+		&cli.StringFlag{
+			Category: "Fetch",
+			Name:     "fetch-remote",
+			Usage:    "Fetch only remotes whose name matches the glob pattern, e.g. \"origin\"",
+		},
+		&cli.BoolFlag{
+			Category: "Fetch",
+			Name:     "probe-remote",
+			Usage:    "List remote branches and tags, as ls-remote does, instead of fetching, all remotes unless fetch selectors are given",
+		},
+		&cli.BoolFlag{
+			Category: "Fetch",
+			Name:     "fetch-prune",
+			Usage:    "Remove remote-tracking branches deleted on the remote when fetching",
+		},
+		&cli.StringFlag{
+			Category: "Fetch",
+			Name:     "fetch-tags",
+			Usage:    "Tags to fetch, \"follow\" tags pointing into fetched history, \"all\" or \"none\"",
+			Value:    "follow",
+		},
+		&cli.DurationFlag{
+			Category: "Fetch",
+			Name:     "fetch-timeout",
+			Usage:    "Timeout of a single fetch attempt, and of every push, 0 for no timeout",
+			Value:    2 * time.Minute,
+		},
+		&cli.IntFlag{
+			Category: "Fetch",
+			Name:     "fetch-jobs",
+			Usage:    "Maximum number of concurrent fetches",
+			Value:    8,
+		},
+		&cli.IntFlag{
+			Category: "Fetch",
+			Name:     "fetch-host-jobs",
+			Usage:    "Maximum number of concurrent fetches from the same host",
+			Value:    4,
+		},
+		&cli.IntFlag{
+			Category: "Fetch",
+			Name:     "fetch-retries",
+			Usage:    "Number of retries of a fetch failed with a transient error",
+			Value:    2,
+		},
+		&cli.DurationFlag{
+			Category: "Fetch",
+			Name:     "fetch-max-age",
+			Usage:    "Skip fetching remotes fetched more recently, e.g. 1h, by assayer or git (FETCH_HEAD)",
+		},
+		&cli.StringSliceFlag{
+			Category: "Fetch",
+			Name:     "ssh-key",
+			Usage:    "Private key file offered to SSH remotes before ssh-agent and ~/.ssh/config keys",
+			EnvVars:  []string{"ASSAYER_SSH_KEYS"},
+		},
 	}
+}
 
+func IndexCommand(action func(c *cli.Context) error) *cli.Command {
+	return &cli.Command{
+		Name:      "index",
+		Usage:     "Show cached repository index",
+		UsageText: "assayer index [root-path...]",
+		Action:    action,
+	}
 }
 
 func ParseFlags(c *cli.Context) (arguments.Arguments, error) {
@@ -145,6 +302,21 @@ func ParseFlags(c *cli.Context) (arguments.Arguments, error) {
 	}
 	args.Deep = c.Bool("deep")
 	args.Verbose = c.Bool("verbose")
+	args.Rescan = c.Bool("rescan")
+	args.Files = c.Bool("files")
+	args.FilesLimit = c.Int("files-limit")
+	args.NoCache = c.Bool("no-cache")
+	switch c.String("backend") {
+	case "go-git":
+		args.Backend = arguments.BackendGoGit
+	case "git":
+		args.Backend = arguments.BackendGit
+	default:
+		return arguments.DefaultArguments(), fmt.Errorf(
+			"unknown backend \"%s\", expected \"go-git\" or \"git\"",
+			c.String("backend"),
+		)
+	}
 	if c.IsSet("reporter") {
 		if args.Count {
 			return arguments.DefaultArguments(), fmt.Errorf(
@@ -162,27 +334,134 @@ func ParseFlags(c *cli.Context) (arguments.Arguments, error) {
 		}
 		args.Reporter = templateTemplate
 	}
-
-	if c.IsSet("fetch-all") && c.IsSet("fetch-group") {
+	args.Format, err = parseFormat(c, args)
+	if err != nil {
+		return arguments.DefaultArguments(), err
+	}
+	args.Count = args.Format == "count"
+	if args.Format == "template" && args.Reporter == nil {
+		return arguments.DefaultArguments(), fmt.Errorf("--format template requires --reporter flag")
+	}
+	if args.Files && slices.Contains([]string{"count", "template", "prometheus"}, args.Format) {
+		return arguments.DefaultArguments(), fmt.Errorf("--files flag conflicts with %s format", args.Format)
+	}
+	switch c.String("sort") {
+	case "", "path":
+		args.Sort = arguments.SortPath
+	case "kind":
+		args.Sort = arguments.SortKind
+	case "age":
+		args.Sort = arguments.SortAge
+	default:
 		return arguments.DefaultArguments(), fmt.Errorf(
-			"--fetch-all and --fetch-group flags conflict with each other",
+			"unknown sort \"%s\", expected \"path\", \"kind\" or \"age\"",
+			c.String("sort"),
 		)
 	}
+	args.Stream = c.Bool("stream")
+	if args.Stream && c.IsSet("sort") {
+		return arguments.DefaultArguments(), fmt.Errorf("--stream and --sort flags conflict with each other")
+	}
+	if args.Stream && args.Format != arguments.FormatDefault {
+		return arguments.DefaultArguments(), fmt.Errorf("--stream flag is supported by the default format only")
+	}
+	// these formats report every verdict of a repository
+	if slices.Contains([]string{"prometheus", "junit", "sarif"}, args.Format) {
+		args.Deep = true
+	}
+
+	for _, selector := range fetchSelectors {
+		if c.IsSet("fetch-all") && c.IsSet(selector) {
+			return arguments.DefaultArguments(), fmt.Errorf(
+				"--fetch-all and --%s flags conflict with each other",
+				selector,
+			)
+		}
+	}
 	if c.IsSet("fetch-all") {
 		args.FetchType = arguments.FetchAll
 	}
-	if c.IsSet("fetch-group") {
-		fetchGroup, err := glob.Compile(c.String("fetch-group"))
-		if err != nil {
-			return arguments.DefaultArguments(), fmt.Errorf("fetch-group is invalid: %s", err)
+	args.SSHKeys = c.StringSlice("ssh-key")
+	args.FetchPrune = c.Bool("fetch-prune")
+	switch c.String("fetch-tags") {
+	case "follow":
+		args.FetchTags = arguments.FetchTagsFollow
+	case "all":
+		args.FetchTags = arguments.FetchTagsAll
+	case "none":
+		args.FetchTags = arguments.FetchTagsNone
+	default:
+		return arguments.DefaultArguments(), fmt.Errorf(
+			"unknown fetch tags \"%s\", expected \"follow\", \"all\" or \"none\"",
+			c.String("fetch-tags"),
+		)
+	}
+	args.FetchTimeout = c.Duration("fetch-timeout")
+	args.FetchJobs = c.Int("fetch-jobs")
+	args.FetchHostJobs = c.Int("fetch-host-jobs")
+	args.FetchRetries = c.Int("fetch-retries")
+	args.FetchMaxAge = c.Duration("fetch-max-age")
+	if args.FetchGroup, err = fetchSelector(c, "fetch-group"); err != nil {
+		return arguments.DefaultArguments(), err
+	}
+	if args.FetchHost, err = fetchSelector(c, "fetch-host"); err != nil {
+		return arguments.DefaultArguments(), err
+	}
+	if args.FetchPath, err = fetchSelector(c, "fetch-path"); err != nil {
+		return arguments.DefaultArguments(), err
+	}
+	if args.FetchRemote, err = fetchSelector(c, "fetch-remote"); err != nil {
+		return arguments.DefaultArguments(), err
+	}
+	for _, selector := range fetchSelectors {
+		if c.IsSet(selector) {
+			args.FetchType = arguments.FetchSome
 		}
-		args.FetchType = arguments.FetchSome
-		args.FetchGroup = &fetchGroup
+	}
+	// without selectors every remote is probed
+	args.ProbeRemote = c.Bool("probe-remote")
+	if args.ProbeRemote && args.FetchType == arguments.FetchNone {
+		args.FetchType = arguments.FetchAll
 	}
 
 	return args, nil
 }
 
+// parseFormat returns the format of the report, --count and --reporter select the count and template formats
+func parseFormat(c *cli.Context, args arguments.Arguments) (string, error) {
+	format := c.String("format")
+	if format == "" {
+		format = arguments.FormatDefault
+	}
+	implied := ""
+	if args.Count {
+		implied = "count"
+	} else if args.Reporter != nil {
+		implied = "template"
+	}
+	if implied == "" {
+		return format, nil
+	}
+	if c.IsSet("format") && format != implied {
+		return "", fmt.Errorf("--format flag conflicts with --count and --reporter flags")
+	}
+	return implied, nil
+}
+
+var fetchSelectors = []string{"fetch-group", "fetch-host", "fetch-path", "fetch-remote"}
+
+// fetchSelector compiles glob of the selector flag, nil if the flag is not set
+func fetchSelector(c *cli.Context, name string) (*glob.Glob, error) {
+	if !c.IsSet(name) {
+		return nil, nil
+	}
+	selector, err := glob.Compile(c.String(name), '/')
+	if err != nil {
+		return nil, fmt.Errorf("%s is invalid: %s", name, err)
+	}
+	return &selector, nil
+}
+
 func parseTypeFlags(c *cli.Context) (arguments.Arguments, error) {
 	if noTypeFlagIsSet(c) && !c.IsSet("all") {
 		return arguments.DefaultArguments(), nil
@@ -194,7 +473,9 @@ func parseTypeFlags(c *cli.Context) (arguments.Arguments, error) {
 		}
 		return arguments.Arguments{
 			Unmodified:      true,
-			Modified:        true,
+			Staged:          true,
+			Unstaged:        true,
+			Conflicted:      true,
 			Untracked:       true,
 			StashedChanges:  true,
 			RemoteBehind:    true,
@@ -205,7 +486,9 @@ func parseTypeFlags(c *cli.Context) (arguments.Arguments, error) {
 
 	return arguments.Arguments{
 		Unmodified:      c.Bool("unmodified"),
-		Modified:        c.Bool("modified"),
+		Staged:          c.Bool("modified") || c.Bool("staged"),
+		Unstaged:        c.Bool("modified") || c.Bool("unstaged"),
+		Conflicted:      c.Bool("modified") || c.Bool("conflicted"),
 		Untracked:       c.Bool("untracked"),
 		StashedChanges:  c.Bool("stashed"),
 		RemoteBehind:    c.Bool("behind-branches"),
@@ -217,6 +500,9 @@ func parseTypeFlags(c *cli.Context) (arguments.Arguments, error) {
 func noTypeFlagIsSet(c *cli.Context) bool {
 	return !c.IsSet("unmodified") &&
 		!c.IsSet("modified") &&
+		!c.IsSet("staged") &&
+		!c.IsSet("unstaged") &&
+		!c.IsSet("conflicted") &&
 		!c.IsSet("untracked") &&
 		!c.IsSet("stashed") &&
 		!c.IsSet("behind-branches") &&
@@ -228,6 +514,465 @@ func anyTypeFlagIsSet(c *cli.Context) bool {
 	return !noTypeFlagIsSet(c)
 }
 
+func PushCommand(action func(c *cli.Context) error) *cli.Command {
+	return &cli.Command{
+		Name:      "push",
+		Usage:     "Push branches with commits not on their remote, and optionally local only branches",
+		UsageText: "assayer push [options] [path-to-check]",
+		Flags: slices.Concat(
+			[]cli.Flag{
+				&cli.BoolFlag{
+					Name:    "local-only-branches",
+					Usage:   "Push local only branches as well",
+					Aliases: []string{"l"},
+				},
+				&cli.StringFlag{
+					Name:  "remote",
+					Usage: "Remote receiving local only branches",
+					Value: "origin",
+				},
+				&cli.BoolFlag{
+					Name:    "set-upstream",
+					Usage:   "Set pushed local only branches to track their remote branch",
+					Aliases: []string{"u"},
+				},
+				&cli.BoolFlag{
+					Name:  "dry-run",
+					Usage: "Show what would be pushed without pushing",
+				},
+				&cli.BoolFlag{
+					Name:    "interactive",
+					Usage:   "Confirm every branch before pushing",
+					Aliases: []string{"i"},
+				},
+			},
+			repositoryFlags(),
+			fetchFlags(),
+		),
+		Action: action,
+	}
+}
+
+// ParsePushFlags parses flags of the push subcommand, checks are limited to what can be pushed
+func ParsePushFlags(c *cli.Context) (arguments.Arguments, arguments.PushArguments, error) {
+	args, err := ParseFlags(c)
+	if err != nil {
+		return args, arguments.PushArguments{}, err
+	}
+	pushArgs := arguments.PushArguments{
+		LocalOnlyBranch: c.Bool("local-only-branches"),
+		Remote:          c.String("remote"),
+		SetUpstream:     c.Bool("set-upstream"),
+		DryRun:          c.Bool("dry-run"),
+		Interactive:     c.Bool("interactive"),
+	}
+	if pushArgs.SetUpstream && !pushArgs.LocalOnlyBranch {
+		return args, pushArgs, fmt.Errorf("--set-upstream flag requires --local-only-branches flag")
+	}
+	onlyChecks(&args)
+	args.RemoteBehind = true
+	args.LocalOnlyBranch = pushArgs.LocalOnlyBranch
+	return args, pushArgs, nil
+}
+
+func PullCommand(action func(c *cli.Context) error) *cli.Command {
+	return &cli.Command{
+		Name:      "pull",
+		Usage:     "Fast-forward branches whose remote is ahead, all remotes are fetched unless fetch selectors are given",
+		UsageText: "assayer pull [options] [path-to-check]",
+		Flags: slices.Concat(
+			[]cli.Flag{
+				&cli.BoolFlag{
+					Name:  "dry-run",
+					Usage: "Show what would be fast-forwarded without changing branches",
+				},
+			},
+			repositoryFlags(),
+			fetchFlags(),
+		),
+		Action: action,
+	}
+}
+
+// ParsePullFlags parses flags of the pull subcommand, checks are limited to branches behind their remote
+func ParsePullFlags(c *cli.Context) (arguments.Arguments, arguments.PullArguments, error) {
+	args, err := ParseFlags(c)
+	if err != nil {
+		return args, arguments.PullArguments{}, err
+	}
+	pullArgs := arguments.PullArguments{
+		DryRun: c.Bool("dry-run"),
+	}
+	if args.ProbeRemote {
+		return args, pullArgs, fmt.Errorf("--probe-remote flag cannot be used with pull, commits have to be fetched")
+	}
+	if args.FetchType == arguments.FetchNone {
+		args.FetchType = arguments.FetchAll
+	}
+	onlyChecks(&args)
+	args.RemoteAhead = true
+	return args, pullArgs, nil
+}
+
+func RescueCommand(action func(c *cli.Context) error) *cli.Command {
+	return &cli.Command{
+		Name:      "rescue",
+		Usage:     "Export unpushed commits, stashes, uncommitted and untracked changes of every repository with findings",
+		UsageText: "assayer rescue --out DIR [options] [path-to-check]",
+		Flags: slices.Concat(
+			[]cli.Flag{
+				&cli.StringFlag{
+					Name:     "out",
+					Usage:    "Directory receiving bundles, patches, tars and manifest.json",
+					Aliases:  []string{"o"},
+					Required: true,
+				},
+			},
+			repositoryFlags(),
+			fetchFlags(),
+		),
+		Action: action,
+	}
+}
+
+// ParseRescueFlags parses flags of the rescue subcommand, every check of unfinished work is enabled
+func ParseRescueFlags(c *cli.Context) (arguments.Arguments, arguments.RescueArguments, error) {
+	args, err := ParseFlags(c)
+	if err != nil {
+		return args, arguments.RescueArguments{}, err
+	}
+	rescueArgs := arguments.RescueArguments{
+		Out: c.String("out"),
+	}
+	onlyChecks(&args)
+	args.Staged = true
+	args.Unstaged = true
+	args.Conflicted = true
+	args.Untracked = true
+	args.StashedChanges = true
+	args.RemoteBehind = true
+	args.LocalOnlyBranch = true
+	return args, rescueArgs, nil
+}
+
+func TuiCommand(action func(c *cli.Context) error) *cli.Command {
+	return &cli.Command{
+		Name:      "tui",
+		Usage:     "Triage repositories with findings in a terminal view, push, pull, drop stashes, delete merged branches or ignore repositories",
+		UsageText: "assayer tui [options] [path-to-check]",
+		Flags: slices.Concat(
+			[]cli.Flag{
+				&cli.StringFlag{
+					Name:  "remote",
+					Usage: "Remote receiving local only branches",
+					Value: "origin",
+				},
+			},
+			checkTypeFlags(),
+			repositoryFlags(),
+			fetchFlags(),
+		),
+		Action: action,
+	}
+}
+
+// ParseTuiFlags parses flags of the tui subcommand, every found verdict and file is listed
+func ParseTuiFlags(c *cli.Context) (arguments.Arguments, arguments.TuiArguments, error) {
+	args, err := ParseFlags(c)
+	tuiArgs := arguments.TuiArguments{Remote: c.String("remote")}
+	if err != nil {
+		return args, tuiArgs, err
+	}
+	if tuiArgs.Remote == "" {
+		return args, tuiArgs, fmt.Errorf("--remote flag must not be empty")
+	}
+	args.Deep = true
+	args.Files = true
+	args.Count = false
+	args.Reporter = nil
+	return args, tuiArgs, nil
+}
+
+func WatchCommand(action func(c *cli.Context) error) *cli.Command {
+	return &cli.Command{
+		Name:      "watch",
+		Usage:     "Keep checking repositories which change, showing a summary or printing a line of JSON per changed repository",
+		UsageText: "assayer watch [options] [path-to-check]",
+		Flags: slices.Concat(
+			watchFlags(),
+			[]cli.Flag{
+				&cli.BoolFlag{
+					Name:  "json",
+					Usage: "Print a line of JSON for every repository whose findings changed",
+				},
+				&cli.BoolFlag{
+					Name:    "files",
+					Usage:   "Report every modified, staged and untracked file",
+					Aliases: []string{"all-files"},
+				},
+			},
+			checkTypeFlags(),
+			repositoryFlags(),
+			fetchFlags(),
+		),
+		Action: action,
+	}
+}
+
+// ParseWatchFlags parses flags of the watch subcommand, every verdict of changed repositories is checked
+func ParseWatchFlags(c *cli.Context) (arguments.Arguments, arguments.WatchArguments, error) {
+	args, err := ParseFlags(c)
+	if err != nil {
+		return args, arguments.WatchArguments{}, err
+	}
+	watchArgs, err := parseWatchFlags(c)
+	if err != nil {
+		return args, watchArgs, err
+	}
+	watchArgs.JSON = c.Bool("json")
+	args.Deep = true
+	args.Count = false
+	args.Reporter = nil
+	return args, watchArgs, nil
+}
+
+// watchFlags control watching repositories, they are shared by watch and daemon subcommands
+func watchFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.DurationFlag{
+			Name:  "debounce",
+			Usage: "How long changes have to settle before repositories are checked again",
+			Value: 500 * time.Millisecond,
+		},
+		&cli.BoolFlag{
+			Name:  "poll",
+			Usage: "Poll repositories instead of watching them with inotify, e.g. on network filesystems",
+		},
+		&cli.DurationFlag{
+			Name:  "poll-interval",
+			Usage: "Interval of polling, also used for repositories which cannot be watched",
+			Value: 5 * time.Second,
+		},
+	}
+}
+
+func parseWatchFlags(c *cli.Context) (arguments.WatchArguments, error) {
+	watchArgs := arguments.WatchArguments{
+		Debounce:     c.Duration("debounce"),
+		Poll:         c.Bool("poll"),
+		PollInterval: c.Duration("poll-interval"),
+	}
+	if watchArgs.PollInterval <= 0 {
+		return watchArgs, fmt.Errorf("--poll-interval flag must be positive")
+	}
+	return watchArgs, nil
+}
+
+func DaemonCommand(action func(c *cli.Context) error) *cli.Command {
+	return &cli.Command{
+		Name:      "daemon",
+		Usage:     "Keep findings up to date, watching repositories and fetching them periodically, and answer queries on a Unix socket",
+		UsageText: "assayer daemon [options] [path-to-check]",
+		Flags: slices.Concat(
+			[]cli.Flag{
+				socketFlag(),
+				&cli.DurationFlag{
+					Name:  "fetch-interval",
+					Usage: "Interval of fetching and checking every repository, 0 to fetch only at start",
+					Value: 15 * time.Minute,
+				},
+			},
+			watchFlags(),
+			checkTypeFlags(),
+			repositoryFlags(),
+			fetchFlags(),
+		),
+		Action: action,
+	}
+}
+
+// ParseDaemonFlags parses flags of the daemon subcommand, all remotes are fetched unless fetch selectors are given
+func ParseDaemonFlags(c *cli.Context) (arguments.Arguments, arguments.DaemonArguments, error) {
+	args, err := ParseFlags(c)
+	if err != nil {
+		return args, arguments.DaemonArguments{}, err
+	}
+	daemonArgs := arguments.DaemonArguments{
+		FetchInterval: c.Duration("fetch-interval"),
+	}
+	daemonArgs.Socket, err = parseSocketFlag(c)
+	if err != nil {
+		return args, daemonArgs, err
+	}
+	daemonArgs.Watch, err = parseWatchFlags(c)
+	if err != nil {
+		return args, daemonArgs, err
+	}
+	if args.FetchType == arguments.FetchNone {
+		args.FetchType = arguments.FetchAll
+	}
+	args.Deep = true
+	args.Count = false
+	args.Reporter = nil
+	return args, daemonArgs, nil
+}
+
+func QueryCommand(action func(c *cli.Context) error) *cli.Command {
+	return &cli.Command{
+		Name:      "query",
+		Usage:     "Report findings kept by the daemon for repositories in or containing the paths",
+		UsageText: "assayer query [--reporter TEMPLATE] [path...]",
+		Flags: []cli.Flag{
+			socketFlag(),
+			&cli.StringFlag{
+				Name:    "reporter",
+				Usage:   "Provide reporter's template",
+				Aliases: []string{"r"},
+			},
+			&cli.BoolFlag{
+				Name:    "verbose",
+				Usage:   "Provide detailed information in the report",
+				Aliases: []string{"v"},
+			},
+		},
+		Action: action,
+	}
+}
+
+// ParseQueryFlags parses flags of the query subcommand
+func ParseQueryFlags(c *cli.Context) (arguments.QueryArguments, error) {
+	queryArgs := arguments.QueryArguments{
+		Verbose: c.Bool("verbose"),
+	}
+	var err error
+	queryArgs.Socket, err = parseSocketFlag(c)
+	if err != nil {
+		return queryArgs, err
+	}
+	if c.IsSet("reporter") {
+		reporterTemplate := c.String("reporter")
+		queryArgs.Reporter, err = template.New("reporter").Parse(reporterTemplate)
+		if err != nil {
+			return queryArgs, fmt.Errorf("reporter template \"%s\" is invalid: %s", reporterTemplate, err)
+		}
+	}
+	return queryArgs, nil
+}
+
+func ServeCommand(action func(c *cli.Context) error) *cli.Command {
+	return &cli.Command{
+		Name:      "serve",
+		Usage:     "Serve findings as JSON and as a dashboard over HTTP, rescanning on request",
+		UsageText: "assayer serve [--listen ADDRESS] [options] [path-to-check]",
+		Flags: slices.Concat(
+			[]cli.Flag{
+				&cli.StringFlag{
+					Name:    "listen",
+					Usage:   "Address the HTTP server listens on",
+					Value:   "127.0.0.1:7457",
+					EnvVars: []string{"ASSAYER_LISTEN"},
+				},
+				&cli.BoolFlag{
+					Name:    "files",
+					Usage:   "Report every modified, staged and untracked file",
+					Aliases: []string{"all-files"},
+				},
+			},
+			checkTypeFlags(),
+			repositoryFlags(),
+			fetchFlags(),
+		),
+		Action: action,
+	}
+}
+
+// ParseServeFlags parses flags of the serve subcommand, every verdict is served
+func ParseServeFlags(c *cli.Context) (arguments.Arguments, arguments.ServeArguments, error) {
+	args, err := ParseFlags(c)
+	if err != nil {
+		return args, arguments.ServeArguments{}, err
+	}
+	serveArgs := arguments.ServeArguments{
+		Listen: c.String("listen"),
+	}
+	if serveArgs.Listen == "" {
+		return args, serveArgs, fmt.Errorf("--listen flag must not be empty")
+	}
+	args.Deep = true
+	args.Count = false
+	args.Reporter = nil
+	return args, serveArgs, nil
+}
+
+func socketFlag() cli.Flag {
+	return &cli.StringFlag{
+		Name:    "socket",
+		Usage:   "Unix socket of the daemon, by default $XDG_RUNTIME_DIR/assayer.sock",
+		EnvVars: []string{"ASSAYER_SOCKET"},
+	}
+}
+
+func parseSocketFlag(c *cli.Context) (string, error) {
+	if c.String("socket") != "" {
+		return c.String("socket"), nil
+	}
+	return cache.SocketPath()
+}
+
+func SnapshotCommand(action func(c *cli.Context) error) *cli.Command {
+	return &cli.Command{
+		Name:      "snapshot",
+		Usage:     "Record index and worktree of every dirty repository as a commit on refs/assayer/wip/<timestamp>",
+		UsageText: "assayer snapshot [options] [path-to-check]",
+		Flags: slices.Concat(
+			[]cli.Flag{
+				&cli.StringFlag{
+					Name:    "backup-remote",
+					Usage:   "Push snapshot refs to this remote of each repository",
+					EnvVars: []string{"ASSAYER_BACKUP_REMOTE"},
+				},
+			},
+			repositoryFlags(),
+			fetchFlags(),
+		),
+		Action: action,
+	}
+}
+
+// ParseSnapshotFlags parses flags of the snapshot subcommand, checks are limited to uncommitted work
+func ParseSnapshotFlags(c *cli.Context) (arguments.Arguments, arguments.SnapshotArguments, error) {
+	args, err := ParseFlags(c)
+	if err != nil {
+		return args, arguments.SnapshotArguments{}, err
+	}
+	snapshotArgs := arguments.SnapshotArguments{
+		Remote: c.String("backup-remote"),
+	}
+	onlyChecks(&args)
+	args.Staged = true
+	args.Unstaged = true
+	args.Conflicted = true
+	args.Untracked = true
+	return args, snapshotArgs, nil
+}
+
+// onlyChecks disables every check and report option, subcommands enable checks they act on
+func onlyChecks(args *arguments.Arguments) {
+	args.Unmodified = false
+	args.Staged = false
+	args.Unstaged = false
+	args.Conflicted = false
+	args.Untracked = false
+	args.StashedChanges = false
+	args.RemoteBehind = false
+	args.RemoteAhead = false
+	args.LocalOnlyBranch = false
+	args.Deep = true
+	args.Count = false
+	args.Files = false
+	args.Reporter = nil
+}
+
 func RootDirectories(c *cli.Context) ([]string, error) {
 	var workingDirectories []string
 	if c.NArg() != 0 {
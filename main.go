@@ -10,26 +10,170 @@ import (
 )
 
 func main() {
-	app := command_line.App(func(c *cli.Context) error {
-		workingDirectories, err := command_line.RootDirectories(c)
-		if err != nil {
-			return err
-		}
-
-		arguments, err := command_line.ParseFlags(c)
-		if err != nil {
-			return err
-		}
-
-		err = assayer.TraverseDirectories(workingDirectories, arguments)
-		if err != nil {
-			return fmt.Errorf("error while traversing\n%s", err)
-		}
-		return nil
-	})
+	app := command_line.App(
+		check,
+		command_line.IndexCommand(index),
+		command_line.PushCommand(push),
+		command_line.PullCommand(pull),
+		command_line.RescueCommand(rescue),
+		command_line.SnapshotCommand(snapshot),
+		command_line.TuiCommand(tui),
+		command_line.WatchCommand(watch),
+		command_line.DaemonCommand(daemon),
+		command_line.QueryCommand(query),
+		command_line.ServeCommand(serve),
+	)
 	err := app.Run(os.Args)
 	if err != nil {
 		println(err.Error())
 		os.Exit(1)
 	}
 }
+
+func check(c *cli.Context) error {
+	workingDirectories, err := command_line.RootDirectories(c)
+	if err != nil {
+		return err
+	}
+
+	arguments, err := command_line.ParseFlags(c)
+	if err != nil {
+		return err
+	}
+
+	err = assayer.TraverseDirectories(workingDirectories, arguments)
+	if err != nil {
+		return fmt.Errorf("error while traversing\n%s", err)
+	}
+	return nil
+}
+
+func index(c *cli.Context) error {
+	return assayer.PrintIndex(c.Args().Slice())
+}
+
+func push(c *cli.Context) error {
+	workingDirectories, err := command_line.RootDirectories(c)
+	if err != nil {
+		return err
+	}
+
+	arguments, pushArguments, err := command_line.ParsePushFlags(c)
+	if err != nil {
+		return err
+	}
+
+	return assayer.Push(workingDirectories, arguments, pushArguments)
+}
+
+func pull(c *cli.Context) error {
+	workingDirectories, err := command_line.RootDirectories(c)
+	if err != nil {
+		return err
+	}
+
+	arguments, pullArguments, err := command_line.ParsePullFlags(c)
+	if err != nil {
+		return err
+	}
+
+	return assayer.Pull(workingDirectories, arguments, pullArguments)
+}
+
+func rescue(c *cli.Context) error {
+	workingDirectories, err := command_line.RootDirectories(c)
+	if err != nil {
+		return err
+	}
+
+	arguments, rescueArguments, err := command_line.ParseRescueFlags(c)
+	if err != nil {
+		return err
+	}
+
+	return assayer.Rescue(workingDirectories, arguments, rescueArguments)
+}
+
+func snapshot(c *cli.Context) error {
+	workingDirectories, err := command_line.RootDirectories(c)
+	if err != nil {
+		return err
+	}
+
+	arguments, snapshotArguments, err := command_line.ParseSnapshotFlags(c)
+	if err != nil {
+		return err
+	}
+
+	return assayer.Snapshot(workingDirectories, arguments, snapshotArguments)
+}
+
+func tui(c *cli.Context) error {
+	workingDirectories, err := command_line.RootDirectories(c)
+	if err != nil {
+		return err
+	}
+
+	arguments, tuiArguments, err := command_line.ParseTuiFlags(c)
+	if err != nil {
+		return err
+	}
+
+	return assayer.Tui(workingDirectories, arguments, tuiArguments)
+}
+
+func watch(c *cli.Context) error {
+	workingDirectories, err := command_line.RootDirectories(c)
+	if err != nil {
+		return err
+	}
+
+	arguments, watchArguments, err := command_line.ParseWatchFlags(c)
+	if err != nil {
+		return err
+	}
+
+	return assayer.Watch(workingDirectories, arguments, watchArguments)
+}
+
+func daemon(c *cli.Context) error {
+	workingDirectories, err := command_line.RootDirectories(c)
+	if err != nil {
+		return err
+	}
+
+	arguments, daemonArguments, err := command_line.ParseDaemonFlags(c)
+	if err != nil {
+		return err
+	}
+
+	return assayer.Daemon(workingDirectories, arguments, daemonArguments)
+}
+
+func query(c *cli.Context) error {
+	workingDirectories, err := command_line.RootDirectories(c)
+	if err != nil {
+		return err
+	}
+
+	queryArguments, err := command_line.ParseQueryFlags(c)
+	if err != nil {
+		return err
+	}
+
+	return assayer.Query(workingDirectories, queryArguments)
+}
+
+func serve(c *cli.Context) error {
+	workingDirectories, err := command_line.RootDirectories(c)
+	if err != nil {
+		return err
+	}
+
+	arguments, serveArguments, err := command_line.ParseServeFlags(c)
+	if err != nil {
+		return err
+	}
+
+	return assayer.Serve(workingDirectories, arguments, serveArguments)
+}
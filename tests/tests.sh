@@ -99,6 +99,7 @@ pop_commit() {
 setup_file() {
   rm -rf tests/repos
   mkdir "tests/repos" -p
+  export XDG_CACHE_HOME="$PWD/tests/repos/cache"
 }
 
 #teardown_file() {
@@ -112,7 +113,7 @@ setup_file() {
   expected="repo1                                                        Unmodified
 repo2                                                        Unmodified
 repo3                                                        Unmodified"
-  result="$(go run . --untracked --unmodified --stashed tests/repos/test1 | sort)"
+  result="$(go run . --untracked --unmodified --stashed tests/repos/test1)"
   echo "$result"
   [ "$result" = "$expected" ]
 }
@@ -125,7 +126,7 @@ repo3                                                        Unmodified"
   expected="repo1                                                        Unmodified
 repo2                                                        Unmodified
 repo3                                                        Unmodified"
-  result="$(go run . --exclude "*/repo4" --untracked --unmodified --stashed tests/repos/test2 | sort)"
+  result="$(go run . --exclude "*/repo4" --untracked --unmodified --stashed tests/repos/test2)"
   echo "$result"
   [ "$result" = "$expected" ]
 }
@@ -142,7 +143,7 @@ repo3                                                        Unmodified"
   expected='repo1                                                        Stashed Changes
 repo2                                                        Stashed Changes
 repo3                                                        Stashed Changes'
-  result="$(go run . --exclude "*/repo4" --untracked --unmodified --stashed tests/repos/test3 | sort)"
+  result="$(go run . --exclude "*/repo4" --untracked --unmodified --stashed tests/repos/test3)"
   echo "$result"
   [ "$result" = "$expected" ]
 }
@@ -175,9 +176,10 @@ repo3/repo                                                   Remote Ahead'
   clone tests/repos/test5/repo3 git@github.com:hov1417/assayer.git
   pop_commit tests/repos/test5/repo3/repo
   make_commit tests/repos/test5/repo3/repo
-  expected='repo1/repo                                                   Modified
-repo1/repo                                                   Remote Ahead
+  expected='repo1/repo                                                   Remote Ahead
+repo1/repo                                                   Staged
 repo1/repo                                                   Stashed Changes
+repo1/repo                                                   Unstaged
 repo1/repo                                                   Untracked
 repo2/repo                                                   Remote Behind
 repo3/repo                                                   Remote Ahead'
@@ -251,7 +253,7 @@ repo1/repo/tests/repos/repo                                  Unmodified'
   touch tests/repos/test10/repo1/ignored/file1.txt
   touch tests/repos/test10/repo1/ignored/file2.txt
   expected='repo1                                                        Local Only Branch'
-  result="$(go run . -d -a tests/repos/test10 | sort)"
+  result="$(go run . -d -a tests/repos/test10)"
   echo "$result"
   [ "$result" = "$expected" ]
 }
@@ -265,7 +267,7 @@ repo1/repo/tests/repos/repo                                  Unmodified'
   touch tests/repos/test11/repo1/ignored/file1.txt
   touch tests/repos/test11/repo1/ignored/file2.txt
   expected='repo1                                                        Local Only Branch'
-  result="$(go run . -d -a tests/repos/test11 | sort)"
+  result="$(go run . -d -a tests/repos/test11)"
   echo "$result"
   [ "$result" = "$expected" ]
 }
@@ -279,7 +281,7 @@ repo1/repo/tests/repos/repo                                  Unmodified'
   touch tests/repos/test12/repo1/ignored/file1.txt
   touch tests/repos/test12/repo1/ignored/file2.txt
   expected='repo1                                                        Local Only Branch'
-  result="$(go run . -d -a tests/repos/test12 | sort)"
+  result="$(go run . -d -a tests/repos/test12)"
   echo "$result"
   [ "$result" = "$expected" ]
 }
@@ -303,7 +305,7 @@ repo1/repo/tests/repos/repo                                  Unmodified'
   touch tests/repos/test14/repo1/ignored/file1.txt
   touch tests/repos/test14/repo1/ignored/file2.txt
   expected='repo1                                                        Local Only Branch'
-  result="$(go run . -d -a tests/repos/test14 | sort)"
+  result="$(go run . -d -a tests/repos/test14)"
   echo "$result"
   [ "$result" = "$expected" ]
 }
@@ -319,3 +321,458 @@ repo1/repo/tests/repos/repo                                  Unmodified'
   echo "$result"
   [ "$result" = "$expected" ]
 }
+
+@test "index" {
+  make_clean tests/repos/test16/repo1
+  make_clean tests/repos/test16/group/repo2
+  go run . --unmodified tests/repos/test16
+  make_clean tests/repos/test16/group/repo3
+  expected='group/repo2                                                  Unmodified
+group/repo3                                                  Unmodified
+repo1                                                        Unmodified'
+  result="$(go run . --unmodified tests/repos/test16)"
+  echo "$result"
+  [ "$result" = "$expected" ]
+  rm -rf tests/repos/test16/repo1
+  expected='group/repo2                                                  Unmodified
+group/repo3                                                  Unmodified'
+  result="$(go run . --unmodified tests/repos/test16)"
+  echo "$result"
+  [ "$result" = "$expected" ]
+}
+
+@test "status cache" {
+  make_clean tests/repos/test17/repo1
+  go run . --modified tests/repos/test17
+  make_dirty tests/repos/test17/repo1
+  expected='repo1                                                        Unstaged'
+  result="$(go run . --modified tests/repos/test17)"
+  echo "$result"
+  [ "$result" = "$expected" ]
+  make_clean tests/repos/test17/repo2
+  touch tests/repos/test17/repo2/excluded.txt
+  go run . --untracked tests/repos/test17/repo2
+  echo "excluded.txt" >> tests/repos/test17/repo2/.git/info/exclude
+  result="$(go run . --untracked tests/repos/test17/repo2)"
+  echo "$result"
+  [ -z "$result" ]
+}
+
+@test "files" {
+  make_clean tests/repos/test18/repo1
+  make_dirty tests/repos/test18/repo1
+  make_staged tests/repos/test18/repo1
+  mkdir -p tests/repos/test18/repo1/new/dir
+  touch tests/repos/test18/repo1/new/dir/file.txt
+  expected='repo1                                                        Staged                                   A  new.txt
+repo1                                                        Unstaged                                  M file.txt
+repo1                                                        Untracked                                ?? new/'
+  result="$(go run . --files --modified --untracked tests/repos/test18)"
+  echo "$result"
+  [ "$result" = "$expected" ]
+}
+
+
+@test "staged and unstaged" {
+  make_clean tests/repos/test19/repo1
+  make_staged tests/repos/test19/repo1
+  make_clean tests/repos/test19/repo2
+  make_dirty tests/repos/test19/repo2
+  make_clean tests/repos/test19/repo3
+  make_staged tests/repos/test19/repo3
+  make_dirty tests/repos/test19/repo3
+  expected='repo1                                                        Staged
+repo2                                                        Unstaged
+repo3                                                        Staged'
+  result="$(go run . --unstaged --staged tests/repos/test19)"
+  echo "$result"
+  [ "$result" = "$expected" ]
+  expected='staged:2,unstaged:2,modified:3'
+  result="$(go run . -d --modified -r 'staged:{{.staged}},unstaged:{{.unstaged}},modified:{{.modified}}' tests/repos/test19)"
+  echo "$result"
+  [ "$result" = "$expected" ]
+}
+
+@test "fetch max age" {
+  make_clean tests/repos/test20/origin
+  clone tests/repos/test20/clone "$PWD/tests/repos/test20/origin"
+  go run . --fetch-all --ahead-branches tests/repos/test20/clone
+  git -C tests/repos/test20/origin commit --allow-empty -m "not pulled"
+  result="$(go run . --fetch-all --fetch-max-age 1h --ahead-branches tests/repos/test20/clone)"
+  echo "$result"
+  [ "$result" = "" ]
+  expected='repo                                                         Remote Ahead'
+  result="$(go run . --fetch-all --ahead-branches tests/repos/test20/clone)"
+  echo "$result"
+  [ "$result" = "$expected" ]
+}
+
+@test "fetch prune" {
+  make_clean tests/repos/test21/origin
+  git -C tests/repos/test21/origin branch deleted
+  clone tests/repos/test21/clone "$PWD/tests/repos/test21/origin"
+  git -C tests/repos/test21/clone/repo branch deleted origin/deleted
+  git -C tests/repos/test21/origin branch -D deleted
+  result="$(go run . --fetch-all --local-only-branches tests/repos/test21/clone)"
+  echo "$result"
+  [ "$result" = "" ]
+  expected='repo                                                         Local Only Branch'
+  result="$(go run . --fetch-all --fetch-prune --local-only-branches tests/repos/test21/clone)"
+  echo "$result"
+  [ "$result" = "$expected" ]
+}
+
+@test "probe remote" {
+  make_clean tests/repos/test22/origin
+  clone tests/repos/test22/clone "$PWD/tests/repos/test22/origin"
+  git -C tests/repos/test22/origin commit --allow-empty -m "not pulled"
+  git -C tests/repos/test22/clone/repo tag not-pushed
+  tracking="$(git -C tests/repos/test22/clone/repo rev-parse origin/master)"
+  expected='repo                                                         Remote Ahead
+repo                                                         Unpushed Tag'
+  result="$(go run . --probe-remote --deep --ahead-branches --behind-branches tests/repos/test22/clone)"
+  echo "$result"
+  [ "$result" = "$expected" ]
+  [ "$(git -C tests/repos/test22/clone/repo rev-parse origin/master)" = "$tracking" ]
+  git -C tests/repos/test22/clone/repo branch backed-up
+  git -C tests/repos/test22/clone/repo remote add backup "$PWD/tests/repos/test22/origin"
+  git -C tests/repos/test22/clone/repo push backup backed-up
+  result="$(go run . --probe-remote --fetch-remote origin --deep --local-only-branches tests/repos/test22/clone)"
+  echo "$result"
+  [ -z "$result" ]
+}
+
+@test "push" {
+  mkdir -p tests/repos/test23
+  git init --bare tests/repos/test23/origin.git
+  clone tests/repos/test23/clone "$PWD/tests/repos/test23/origin.git"
+  make_commit tests/repos/test23/clone/repo
+  git -C tests/repos/test23/clone/repo push origin HEAD:master
+  git -C tests/repos/test23/clone/repo commit --allow-empty -m "not pushed"
+  git -C tests/repos/test23/clone/repo branch local
+  expected='repo                                                         Would Push                               master -> origin/master'
+  result="$(go run . push --dry-run tests/repos/test23/clone)"
+  echo "$result"
+  [ "$result" = "$expected" ]
+  expected='repo                                                         Pushed                                   local -> origin/local
+repo                                                         Pushed                                   master -> origin/master'
+  result="$(go run . push --local-only-branches --set-upstream tests/repos/test23/clone | sort)"
+  echo "$result"
+  [ "$result" = "$expected" ]
+  result="$(go run . --behind-branches --local-only-branches tests/repos/test23/clone)"
+  echo "$result"
+  [ "$result" = "" ]
+  [ "$(git -C tests/repos/test23/clone/repo config branch.local.remote)" = "origin" ]
+}
+
+@test "pull" {
+  make_clean tests/repos/test24/origin
+  git -C tests/repos/test24/origin branch side
+  git -C tests/repos/test24/origin branch diverged
+  clone tests/repos/test24/clone "$PWD/tests/repos/test24/origin"
+  git -C tests/repos/test24/clone/repo branch side origin/side
+  git -C tests/repos/test24/clone/repo branch diverged origin/diverged
+  git -C tests/repos/test24/clone/repo checkout diverged
+  git -C tests/repos/test24/clone/repo commit --allow-empty -m "local"
+  git -C tests/repos/test24/clone/repo checkout master
+  for branch in master side diverged; do
+    git -C tests/repos/test24/origin checkout "$branch"
+    git -C tests/repos/test24/origin commit --allow-empty -m "not pulled"
+  done
+  expected='repo                                                         Pulled                                   master <- origin/master
+repo                                                         Pulled                                   side <- origin/side
+repo                                                         Skipped                                  diverged <- origin/diverged: branch has diverged from remote'
+  result="$(go run . pull tests/repos/test24/clone | sort)"
+  echo "$result"
+  [ "$result" = "$expected" ]
+  [ "$(git -C tests/repos/test24/clone/repo rev-parse master)" = "$(git -C tests/repos/test24/origin rev-parse master)" ]
+  [ "$(git -C tests/repos/test24/clone/repo rev-parse side)" = "$(git -C tests/repos/test24/origin rev-parse side)" ]
+  [ -z "$(git -C tests/repos/test24/clone/repo status --porcelain)" ]
+  git -C tests/repos/test24/origin checkout master
+  git -C tests/repos/test24/origin commit --allow-empty -m "not pulled"
+  make_dirty tests/repos/test24/clone/repo
+  result="$(go run . pull tests/repos/test24/clone | grep master)"
+  echo "$result"
+  [ "$result" = 'repo                                                         Skipped                                  master <- origin/master: worktree has uncommitted or untracked files' ]
+  git -C tests/repos/test24/clone/repo worktree add "$PWD/tests/repos/test24/side" side
+  git -C tests/repos/test24/origin checkout side
+  git -C tests/repos/test24/origin commit --allow-empty -m "not pulled"
+  side="$(git -C tests/repos/test24/clone/repo rev-parse side)"
+  result="$(go run . pull tests/repos/test24/clone | grep side)"
+  echo "$result"
+  [ "$result" = "repo                                                         Skipped                                  side <- origin/side: branch is checked out in worktree $PWD/tests/repos/test24/side" ]
+  [ "$(git -C tests/repos/test24/clone/repo rev-parse side)" = "$side" ]
+  [ -z "$(git -C tests/repos/test24/side status --porcelain)" ]
+}
+
+@test "rescue" {
+  make_clean tests/repos/test25/repo1
+  git -C tests/repos/test25/repo1 commit --allow-empty -m "lost"
+  git -C tests/repos/test25/repo1 reset --hard HEAD~1
+  git -C tests/repos/test25/repo1 checkout -b deleted
+  git -C tests/repos/test25/repo1 commit --allow-empty -m "deleted 1"
+  git -C tests/repos/test25/repo1 commit --allow-empty -m "deleted 2"
+  git -C tests/repos/test25/repo1 checkout -
+  git -C tests/repos/test25/repo1 branch -D deleted
+  make_stashed tests/repos/test25/repo1
+  make_dirty tests/repos/test25/repo1
+  make_untracked tests/repos/test25/repo1
+  make_clean tests/repos/test25/repo2
+  expected='repo1                                                        Rescued                                  test25/repo1/changes.patch
+repo1                                                        Rescued                                  test25/repo1/commits.bundle
+repo1                                                        Rescued                                  test25/repo1/untracked.tar
+repo2                                                        Rescued                                  test25/repo2/commits.bundle'
+  result="$(go run . rescue --out tests/repos/rescue25 tests/repos/test25 | sort)"
+  echo "$result"
+  [ "$result" = "$expected" ]
+  heads="$(git bundle list-heads tests/repos/rescue25/test25/repo1/commits.bundle)"
+  echo "$heads"
+  [ "$(echo "$heads" | grep -c refs/assayer/rescue/stash/)" = "1" ]
+  [ "$(echo "$heads" | grep -c refs/assayer/rescue/lost/)" = "2" ]
+  grep -q "changed but not staged" tests/repos/rescue25/test25/repo1/changes.patch
+  tar tf tests/repos/rescue25/test25/repo1/untracked.tar | grep -q "^file[0-9]*.txt$"
+  grep -q '"kind": "bundle"' tests/repos/rescue25/manifest.json
+  [ -z "$(git -C tests/repos/test25/repo1 for-each-ref refs/assayer)" ]
+  result="$(go run . rescue --out tests/repos/rescue25 tests/repos/test25 2>&1 || true)"
+  echo "$result"
+  echo "$result" | grep -q "already exists"
+  make_clean tests/repos/test25/a/src/repo
+  make_untracked tests/repos/test25/a/src/repo
+  make_clean tests/repos/test25/b/src/repo
+  make_untracked tests/repos/test25/b/src/repo
+  go run . rescue --out tests/repos/rescue25b tests/repos/test25/a/src tests/repos/test25/b/src
+  [ -f tests/repos/rescue25b/src/repo/untracked.tar ]
+  [ -f tests/repos/rescue25b/src-2/repo/untracked.tar ]
+}
+
+@test "snapshot" {
+  mkdir -p tests/repos/test26
+  git init --bare tests/repos/test26/origin.git
+  clone tests/repos/test26/work/clone "$PWD/tests/repos/test26/origin.git"
+  make_commit tests/repos/test26/work/clone/repo
+  make_staged tests/repos/test26/work/clone/repo
+  make_dirty tests/repos/test26/work/clone/repo
+  make_untracked tests/repos/test26/work/clone/repo
+  make_clean tests/repos/test26/work/clean
+  head="$(git -C tests/repos/test26/work/clone/repo rev-parse HEAD)"
+  status="$(git -C tests/repos/test26/work/clone/repo status --porcelain)"
+  staged="$(git -C tests/repos/test26/work/clone/repo diff --cached)"
+  result="$(go run . snapshot --backup-remote origin tests/repos/test26/work)"
+  echo "$result"
+  [ "$(echo "$result" | wc -l)" = "1" ]
+  echo "$result" | grep -q "^clone/repo  *Snapshot  *[0-9a-f]\{7\} refs/assayer/wip/[0-9T.Z]* -> origin$"
+  [ "$(git -C tests/repos/test26/work/clone/repo rev-parse HEAD)" = "$head" ]
+  [ "$(git -C tests/repos/test26/work/clone/repo status --porcelain)" = "$status" ]
+  [ "$(git -C tests/repos/test26/work/clone/repo diff --cached)" = "$staged" ]
+  ref="$(git -C tests/repos/test26/work/clone/repo for-each-ref --format='%(refname)' refs/assayer/wip)"
+  [ "$(git -C tests/repos/test26/work/clone/repo show "$ref:file.txt")" = "changed but not staged" ]
+  [ "$(git -C tests/repos/test26/work/clone/repo show "$ref^2:new.txt")" = "staged only" ]
+  git -C tests/repos/test26/work/clone/repo ls-tree --name-only "$ref" | grep -q "^file[0-9]*.txt$"
+  [ "$(git -C tests/repos/test26/origin.git for-each-ref --format='%(refname)' refs/assayer/wip)" = "$ref" ]
+  [ -z "$(git -C tests/repos/test26/work/clean for-each-ref refs/assayer)" ]
+  git -C tests/repos/test26/work/clone/repo reset --hard
+  git -C tests/repos/test26/work/clone/repo clean -fdq
+  git -C tests/repos/test26/work/clone/repo stash apply --index "$ref"
+  [ "$(git -C tests/repos/test26/work/clone/repo status --porcelain)" = "$status" ]
+
+  make_clean tests/repos/test26/local/conflicted
+  git -C tests/repos/test26/local/conflicted checkout -b other
+  echo "other" > tests/repos/test26/local/conflicted/file.txt
+  git -C tests/repos/test26/local/conflicted commit -am "other commit"
+  git -C tests/repos/test26/local/conflicted checkout -
+  echo "main" > tests/repos/test26/local/conflicted/file.txt
+  git -C tests/repos/test26/local/conflicted commit -am "main commit"
+  git -C tests/repos/test26/local/conflicted merge other || true
+  mkdir -p tests/repos/test26/local/unborn
+  git init tests/repos/test26/local/unborn
+  echo "first" > tests/repos/test26/local/unborn/first.txt
+  git -C tests/repos/test26/local/unborn add first.txt
+  result="$(go run . snapshot tests/repos/test26/local)"
+  echo "$result"
+  [ "$(echo "$result" | grep -c "  *Snapshot  *[0-9a-f]\{7\} refs/assayer/wip/[0-9T.Z]*$")" = "2" ]
+  ref="$(git -C tests/repos/test26/local/conflicted for-each-ref --format='%(refname)' refs/assayer/wip)"
+  git -C tests/repos/test26/local/conflicted show "$ref^2:file.txt" | grep -q "^<<<<<<<"
+  git -C tests/repos/test26/local/conflicted merge --abort
+  git -C tests/repos/test26/local/conflicted stash apply --index "$ref"
+  [ "$(git -C tests/repos/test26/local/conflicted status --porcelain)" = "M  file.txt" ]
+  ref="$(git -C tests/repos/test26/local/unborn for-each-ref --format='%(refname)' refs/assayer/wip)"
+  git -C tests/repos/test26/local/unborn rm --cached --quiet first.txt
+  rm tests/repos/test26/local/unborn/first.txt
+  git -C tests/repos/test26/local/unborn stash apply --index "$ref"
+  [ "$(git -C tests/repos/test26/local/unborn status --porcelain)" = "A  first.txt" ]
+}
+
+@test "ignore" {
+  make_clean tests/repos/test27/repo1
+  make_untracked tests/repos/test27/repo1
+  make_clean tests/repos/test27/repo2
+  make_untracked tests/repos/test27/repo2
+  git -C tests/repos/test27/repo2 config assayer.ignore true
+  expected='repo1                                                        Untracked'
+  result="$(go run . tests/repos/test27)"
+  echo "$result"
+  [ "$result" = "$expected" ]
+  result="$(go run . rescue --out tests/repos/rescue27 tests/repos/test27)"
+  echo "$result"
+  echo "$result" | grep -q "^repo2  *Rescued  *test27/repo2/untracked.tar$"
+  result="$(go run . tui tests/repos/test27 < /dev/null 2>&1 || true)"
+  echo "$result"
+  [ "$result" = "tui requires a terminal" ]
+}
+
+@test "watch" {
+  make_clean tests/repos/test28/repo1
+  make_clean tests/repos/test28/repo2
+  make_untracked tests/repos/test28/repo2
+  for mode in --debounce=100ms --poll; do
+    rm -f tests/repos/watch28.out
+    go run . watch --json "$mode" --poll-interval 200ms --untracked --unstaged tests/repos/test28 > tests/repos/watch28.out &
+    for _ in $(seq 100); do [ -s tests/repos/watch28.out ] && break; sleep 0.3; done
+    echo "$mode" >> tests/repos/test28/repo1/file.txt
+    for _ in $(seq 50); do [ "$(wc -l < tests/repos/watch28.out)" -ge 2 ] && break; sleep 0.2; done
+    pkill -f "[w]atch --json $mode" || true
+    cat tests/repos/watch28.out
+    [ "$(wc -l < tests/repos/watch28.out)" = "2" ]
+    sed -n 1p tests/repos/watch28.out | grep -q '"repository":"repo2",.*"kind":"Untracked"'
+    sed -n 2p tests/repos/watch28.out | grep -q '"repository":"repo1",.*"kind":"Unstaged"'
+    git -C tests/repos/test28/repo1 checkout file.txt
+  done
+}
+
+@test "daemon" {
+  make_clean tests/repos/test29/repo1
+  make_clean tests/repos/test29/repo2
+  make_untracked tests/repos/test29/repo2
+  go run . daemon --socket tests/repos/daemon29.sock --fetch-interval 0 --debounce 100ms --untracked --unstaged tests/repos/test29 &
+  for _ in $(seq 100); do go run . query --socket tests/repos/daemon29.sock && break; sleep 0.3; done
+  expected='repo2                                                        Untracked'
+  result="$(go run . query --socket tests/repos/daemon29.sock)"
+  echo "$result"
+  [ "$result" = "$expected" ]
+  make_dirty tests/repos/test29/repo1
+  for _ in $(seq 50); do [ "$(go run . query --socket tests/repos/daemon29.sock | wc -l)" = "2" ] && break; sleep 0.2; done
+  result="$(go run . query --socket tests/repos/daemon29.sock --reporter '{{.untracked}} {{.unstaged}}')"
+  echo "$result"
+  [ "$result" = "1 1" ]
+  expected='repo1                                                        Unstaged'
+  result="$(go run . query --socket tests/repos/daemon29.sock tests/repos/test29/repo1/missing)"
+  echo "$result"
+  pkill -f "[d]aemon --socket tests/repos/daemon29.sock" || true
+  [ "$result" = "$expected" ]
+}
+
+@test "serve" {
+  make_clean tests/repos/test30/repo1
+  make_clean tests/repos/test30/repo2
+  make_untracked tests/repos/test30/repo2
+  go run . serve --listen 127.0.0.1:17430 --untracked --unstaged tests/repos/test30 &
+  for _ in $(seq 100); do curl -sf http://127.0.0.1:17430/api/scan > /dev/null && break; sleep 0.3; done
+  result="$(curl -s http://127.0.0.1:17430/api/repositories | jq -r '.[] | .repository + " " + (.kinds | join(","))')"
+  echo "$result"
+  [ "$result" = "test30/repo2 Untracked" ]
+  make_dirty tests/repos/test30/repo1
+  result="$(curl -s -X POST http://127.0.0.1:17430/api/scan | jq -r '"\(.repositories) \(.verdicts)"')"
+  echo "$result"
+  [ "$result" = "2 2" ]
+  result="$(curl -s http://127.0.0.1:17430/api/verdicts | jq -r '.[0].kind')"
+  echo "$result"
+  [ "$result" = "Unstaged" ]
+  result="$(curl -s http://127.0.0.1:17430/ | grep -c '<title>Assayer</title>')"
+  rebound="$(curl -s -o /dev/null -w '%{http_code}' -H 'Host: attacker.example:17430' http://127.0.0.1:17430/api/verdicts)"
+  crossOrigin="$(curl -s -o /dev/null -w '%{http_code}' -X POST -H 'Origin: http://attacker.example' http://127.0.0.1:17430/api/scan)"
+  sameOrigin="$(curl -s -o /dev/null -w '%{http_code}' -X POST -H 'Origin: http://localhost:17430' -H 'Host: localhost:17430' http://127.0.0.1:17430/api/scan)"
+  pkill -f "[s]erve --listen 127.0.0.1:17430" || true
+  [ "$result" = "1" ]
+  [ "$rebound" = "403" ]
+  [ "$crossOrigin" = "403" ]
+  [ "$sameOrigin" = "200" ]
+}
+
+@test "prometheus" {
+  make_clean tests/repos/test31/repo1
+  make_clean tests/repos/test31/repo2
+  make_untracked tests/repos/test31/repo2
+  make_dirty tests/repos/test31/repo2
+  result="$(go run . --format prometheus -tU tests/repos/test31 | grep -v '^#' | grep -v '^assayer_scan_')"
+  root="$(cd tests/repos/test31 && pwd)"
+  expected="assayer_repositories_total 2
+assayer_repositories_with_findings 1
+assayer_verdicts{kind=\"unstaged\",root=\"$root\"} 1
+assayer_verdicts{kind=\"untracked\",root=\"$root\"} 1
+assayer_check_errors_total 0"
+  echo "$result"
+  [ "$result" = "$expected" ]
+  result="$(go run . --format prometheus --count tests/repos/test31 2>&1 || true)"
+  [ "$result" = "--format flag conflicts with --count and --reporter flags" ]
+}
+
+@test "junit and sarif" {
+  make_clean tests/repos/test32/repo1
+  make_clean tests/repos/test32/repo2
+  make_untracked tests/repos/test32/repo2
+  make_dirty tests/repos/test32/repo2
+  result="$(go run . --format junit -tU tests/repos/test32)"
+  echo "$result"
+  [ "$(echo "$result" | grep -c '<testcase ')" = "2" ]
+  echo "$result" | grep -q '<testsuites name="assayer" tests="2" failures="1" errors="0"'
+  echo "$result" | grep -q '<failure type="Untracked"'
+  echo "$result" | grep -q '<failure type="Unstaged"'
+  result="$(go run . --format sarif -tU tests/repos/test32 | jq -r '.runs[0].results[] | .ruleId + " " + .locations[0].physicalLocation.artifactLocation.uri')"
+  echo "$result"
+  [[ "$result" =~ ^"unstaged repo2/file.txt
+untracked repo2/file"[0-9]+".txt"$ ]]
+}
+
+@test "table and tree" {
+  make_clean tests/repos/test33/repo1
+  make_clean tests/repos/test33/a-repository-with-a-rather-long-name
+  make_dirty tests/repos/test33/repo1
+  make_staged tests/repos/test33/a-repository-with-a-rather-long-name
+  expected='REPOSITORY                            KIND      DETAILS
+a-repository-with-a-rather-long-name  Staged    File "new.txt" is Added
+repo1                                 Unstaged  File "file.txt" is Modified'
+  result="$(go run . --format table -v -SU tests/repos/test33)"
+  echo "$result"
+  [ "$result" = "$expected" ]
+  expected='REPOSITORY                KIND      DETAILS
+…with-a-rather-long-name  Staged    File "new.t…
+repo1                     Unstaged  File "file.…'
+  result="$(COLUMNS=48 go run . --format table -v -SU tests/repos/test33)"
+  echo "$result"
+  [ "$result" = "$expected" ]
+  root="$(cd tests/repos/test33 && pwd)"
+  branch="$(git -C tests/repos/test33/repo1 branch --show-current)"
+  expected="$root
+├── a-repository-with-a-rather-long-name  $branch
+│   └── Staged  A  new.txt
+└── repo1  $branch
+    └── Unstaged   M file.txt"
+  result="$(go run . --format tree -SU tests/repos/test33)"
+  echo "$result"
+  [ "$result" = "$expected" ]
+}
+
+@test "sort" {
+  make_clean tests/repos/test34/repo1
+  make_clean tests/repos/test34/repo2
+  make_untracked tests/repos/test34/repo1
+  make_staged tests/repos/test34/repo2
+  touch -d '2020-01-01' tests/repos/test34/repo2/new.txt
+  expected='repo1                                                        Untracked
+repo2                                                        Staged'
+  result="$(go run . -tS tests/repos/test34)"
+  echo "$result"
+  [ "$result" = "$expected" ]
+  expected='repo2                                                        Staged
+repo1                                                        Untracked'
+  result="$(go run . -tS --sort kind tests/repos/test34)"
+  echo "$result"
+  [ "$result" = "$expected" ]
+  result="$(go run . -tS --sort age tests/repos/test34)"
+  echo "$result"
+  [ "$result" = "$expected" ]
+  result="$(go run . -tS --stream tests/repos/test34 | sort)"
+  [ "$(echo "$result" | wc -l)" = "2" ]
+  result="$(go run . --stream --sort kind tests/repos/test34 2>&1 || true)"
+  [ "$result" = "--stream and --sort flags conflict with each other" ]
+}
@@ -2,6 +2,7 @@ package types
 
 import (
 	"path"
+	"path/filepath"
 
 	"github.com/go-git/go-git/v5"
 )
@@ -9,6 +10,8 @@ import (
 type Verdict interface {
 	Repository() string
 	RepositoryPath() string
+	// FullPath is the path of the repository on disk
+	FullPath() string
 }
 
 func RepoName(v Verdict, detailed bool) string {
@@ -20,13 +23,12 @@ func RepoName(v Verdict, detailed bool) string {
 }
 
 type Unmodified struct {
-	base       string
+	directory  string
 	repository string
 }
 
 func NewUnmodified(directory, repository string) Unmodified {
-	base := path.Base(directory)
-	return Unmodified{base: base, repository: repository}
+	return Unmodified{directory: directory, repository: repository}
 }
 
 func (u Unmodified) Repository() string {
@@ -34,7 +36,11 @@ func (u Unmodified) Repository() string {
 }
 
 func (u Unmodified) RepositoryPath() string {
-	return path.Join(u.base, u.repository)
+	return path.Join(path.Base(u.directory), u.repository)
+}
+
+func (u Unmodified) FullPath() string {
+	return filepath.Join(u.directory, u.repository)
 }
 
 func Stringify(status git.StatusCode) string {